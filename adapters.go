@@ -0,0 +1,180 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSort is returned when the sort parameters from the clients aren't
+// allowed, see ListParams.
+var ErrInvalidSort = errors.New("pg: invalid sort")
+
+// ListParams is the allowlist of the List parameters parsed from the query
+// string of a request, i.e.
+//
+//   - page and per_page, the pagination (see OffsetPagination.Validate);
+//   - sort_by and sort_order (asc or desc), or sort (see ParseSort), the
+//     sorting;
+//   - the filters, equality conditions on the columns, a repeated parameter
+//     matches any of its values.
+//
+// The fields and the filters are mapped to the columns, so that no input from
+// the clients goes into the query but the bound values.
+type ListParams struct {
+	DefaultPerPage int64
+	Sorts          map[string]string // sort_by values to the columns
+	Filters        map[string]string // parameters to the columns
+}
+
+// GinContext is the part of *gin.Context read by FromGin.
+type GinContext interface {
+	QueryArray(key string) []string
+}
+
+// EchoContext is the part of echo.Context read by FromEcho.
+type EchoContext interface {
+	QueryParams() url.Values
+}
+
+// FromGin parses the List parameters from the query string of the gin
+// request.
+//
+// Example:
+//
+//	func listUsers(c *gin.Context) {
+//		opts, err := pg.FromGin(c, userParams)
+//		if err != nil {
+//			c.AbortWithError(http.StatusBadRequest, err)
+//			return
+//		}
+//		result, err := pg.ListItems[*User](c, pg.SQL.Select("*").From("users"), opts...)
+//		...
+//	}
+func FromGin(c GinContext, params ListParams) ([]ListOption, error) {
+	return params.parse(c.QueryArray)
+}
+
+// FromEcho parses the List parameters from the query string of the echo
+// request.
+func FromEcho(c EchoContext, params ListParams) ([]ListOption, error) {
+	query := c.QueryParams()
+	return params.parse(func(key string) []string { return query[key] })
+}
+
+// FromChi parses the List parameters from the query string of the request,
+// of chi or any other net/http router.
+//
+// Example:
+//
+//	var userParams = pg.ListParams{
+//		DefaultPerPage: 20,
+//		Sorts:          map[string]string{"name": "name", "created": "created_at"},
+//		Filters:        map[string]string{"role": "role", "team": "team_id"},
+//	}
+//
+//	// GET /users?page=2&sort_by=created&sort_order=desc&role=admin&role=owner
+//	opts, err := pg.FromChi(r, userParams)
+func FromChi(r *http.Request, params ListParams) ([]ListOption, error) {
+	query := r.URL.Query()
+	return params.parse(func(key string) []string { return query[key] })
+}
+
+func (params ListParams) parse(values func(key string) []string) ([]ListOption, error) {
+	get := func(key string) string {
+		if vs := values(key); len(vs) > 0 {
+			return vs[0]
+		}
+		return ""
+	}
+
+	filters := make([]string, 0, len(params.Filters))
+	for param := range params.Filters {
+		filters = append(filters, param)
+	}
+	sort.Strings(filters)
+
+	var opts []ListOption
+	for _, param := range filters {
+		if vs := values(param); len(vs) > 0 {
+			opts = append(opts, With(params.Filters[param], vs...))
+		}
+	}
+
+	if raw := get("sort"); raw != "" {
+		sorts, err := ParseSort(raw, params.Sorts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sorts...)
+	} else if sortBy := get("sort_by"); sortBy != "" {
+		column, ok := params.Sorts[sortBy]
+		if !ok {
+			return nil, fmt.Errorf("%w: sort_by %q", ErrInvalidSort, sortBy)
+		}
+		direction := strings.ToLower(get("sort_order"))
+		if direction == "" {
+			direction = "asc"
+		}
+		if direction != "asc" && direction != "desc" {
+			return nil, fmt.Errorf("%w: sort_order %q", ErrInvalidSort, direction)
+		}
+		opts = append(opts, WithSortBy(column, direction))
+	}
+
+	pagination, err := offsetPaginationFromQuery(get, params.DefaultPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, WithOffsetPagination(pagination)), nil
+}
+
+// ParseSort parses the sort parameter from the clients, a comma-separated
+// list of the fields, each descending if prefixed by "-", e.g.
+// "-created_at,name", against the allowlist mapping the fields to the
+// columns. The empty items are skipped, e.g. of "name,". An unknown, repeated
+// or empty field (e.g. "-") is rejected with ErrInvalidSort, so that no input
+// from the clients goes into ORDER BY.
+//
+// Example:
+//
+//	// GET /users?sort=-created,name
+//	sorts, err := pg.ParseSort(r.URL.Query().Get("sort"), map[string]string{
+//		"created": "created_at",
+//		"name":    "name",
+//	})
+func ParseSort(raw string, allowed map[string]string) ([]ListOption, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var opts []ListOption
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		direction := "asc"
+		if strings.HasPrefix(field, "-") {
+			field, direction = field[1:], "desc"
+		} else {
+			field = strings.TrimPrefix(field, "+")
+		}
+		if field == "" {
+			return nil, fmt.Errorf("%w: empty field in %q", ErrInvalidSort, raw)
+		}
+		column, ok := allowed[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q", ErrInvalidSort, field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: field %q more than once", ErrInvalidSort, field)
+		}
+		seen[field] = true
+		opts = append(opts, WithSortBy(column, direction))
+	}
+	return opts, nil
+}
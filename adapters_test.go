@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := map[string]string{
+		"created": "created_at",
+		"name":    "name",
+	}
+	for _, tc := range []struct {
+		raw     string
+		want    string // the ORDER BY clause
+		wantErr bool
+	}{
+		{raw: "", want: ""},
+		{raw: "name", want: "ORDER BY name asc"},
+		{raw: "+name", want: "ORDER BY name asc"},
+		{raw: "-created,name", want: "ORDER BY created_at desc, name asc"},
+		{raw: " -created , +name ", want: "ORDER BY created_at desc, name asc"},
+		{raw: "name,", want: "ORDER BY name asc"},
+		{raw: ",,name,,", want: "ORDER BY name asc"},
+		{raw: ",", want: ""},
+		{raw: "created_at", wantErr: true},
+		{raw: "name;DROP TABLE users", wantErr: true},
+		{raw: "name,-name", wantErr: true},
+		{raw: "name,name", wantErr: true},
+		{raw: "-", wantErr: true},
+		{raw: "name,+", wantErr: true},
+		{raw: "--name", wantErr: true},
+	} {
+		opts, err := ParseSort(tc.raw, allowed)
+		if tc.wantErr {
+			if !errors.Is(err, ErrInvalidSort) {
+				t.Errorf("ParseSort(%q) = %v, want ErrInvalidSort", tc.raw, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSort(%q) = %v", tc.raw, err)
+			continue
+		}
+
+		query := SQL.Select("*").From("users")
+		for _, opt := range opts {
+			query = opt.Apply(query)
+		}
+		sqlstr, _, err := query.ToSql()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "SELECT * FROM users"
+		if tc.want != "" {
+			want += " " + tc.want
+		}
+		if sqlstr != want {
+			t.Errorf("ParseSort(%q) query = %q, want %q", tc.raw, sqlstr, want)
+		}
+	}
+}
+
+func TestParseSortEmptyFieldError(t *testing.T) {
+	_, err := ParseSort("name,-", map[string]string{"name": "name"})
+	if want := `pg: invalid sort: empty field in "name,-"`; err == nil || err.Error() != want {
+		t.Errorf("ParseSort error = %v, want %s", err, want)
+	}
+}
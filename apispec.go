@@ -0,0 +1,109 @@
+package pg
+
+import "net/url"
+
+// JSONAPIPaging is the top-level links and meta members of a JSON:API
+// document for a page of a collection. Embed it in the document struct.
+// See https://jsonapi.org/format/#fetching-pagination
+//
+// Example:
+//
+//	doc := struct {
+//		Data []*User `json:"data"`
+//		pg.JSONAPIPaging
+//	}{users, pagination.JSONAPI(pg.RequestURL(r))}
+//	json.NewEncoder(w).Encode(doc)
+type JSONAPIPaging struct {
+	Links JSONAPILinks `json:"links"`
+	Meta  any          `json:"meta"`
+}
+
+// JSONAPILinks is the pagination links of a JSON:API document. The links to
+// the unavailable pages are omitted.
+type JSONAPILinks struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// HALLink is a link object of HAL.
+type HALLink struct {
+	Href string `json:"href"`
+}
+
+// HALLinks is the _links of a HAL resource, keyed by the relations. See
+// https://datatracker.ietf.org/doc/html/draft-kelly-json-hal
+//
+// Example:
+//
+//	resource := struct {
+//		Links    pg.HALLinks `json:"_links"`
+//		Embedded struct {
+//			Users []*User `json:"users"`
+//		} `json:"_embedded"`
+//		*pg.OffsetPagination
+//	}{Links: pagination.HAL(pg.RequestURL(r)), OffsetPagination: pagination}
+//	resource.Embedded.Users = users
+type HALLinks map[string]HALLink
+
+// JSONAPI renders the links (see Links) and the meta of the pagination of the
+// URL in the JSON:API format. The meta is the pagination itself, e.g.
+// {"page": 2, "per_page": 20, "count_pages": 5, "count_records": 90, ...}.
+func (p *OffsetPagination) JSONAPI(theURL *url.URL) JSONAPIPaging {
+	links := p.Links(theURL)
+	return JSONAPIPaging{
+		Links: JSONAPILinks{
+			Self:  linkURL(theURL, theURL.Query()).String(),
+			First: urlString(links.First),
+			Prev:  urlString(links.Prev),
+			Next:  urlString(links.Next),
+			Last:  urlString(links.Last),
+		},
+		Meta: p,
+	}
+}
+
+// HAL renders the links (see Links) of the pagination of the URL as HAL
+// links, e.g. {"self": {"href": "..."}, "next": {"href": "..."}}.
+func (p *OffsetPagination) HAL(theURL *url.URL) HALLinks {
+	return halLinks(theURL, p.Links(theURL))
+}
+
+// JSONAPI renders the links (see Links) and the meta of the pagination of the
+// URL in the JSON:API format. The meta is {"limit": ..., "cursor": ...}, the
+// cursor of the next page.
+func (p *SeekPagination) JSONAPI(theURL *url.URL) JSONAPIPaging {
+	links := p.Links(theURL)
+	return JSONAPIPaging{
+		Links: JSONAPILinks{
+			Self: linkURL(theURL, theURL.Query()).String(),
+			Next: urlString(links.Next),
+		},
+		Meta: map[string]any{"limit": p.Limit(), "cursor": p.Cursor()},
+	}
+}
+
+// HAL renders the links (see Links) of the pagination of the URL as HAL
+// links.
+func (p *SeekPagination) HAL(theURL *url.URL) HALLinks {
+	return halLinks(theURL, p.Links(theURL))
+}
+
+func halLinks(theURL *url.URL, links PageLinks) HALLinks {
+	hal := HALLinks{"self": {Href: linkURL(theURL, theURL.Query()).String()}}
+	for rel, link := range map[string]*url.URL{"first": links.First, "prev": links.Prev, "next": links.Next, "last": links.Last} {
+		if link != nil {
+			hal[rel] = HALLink{Href: link.String()}
+		}
+	}
+	return hal
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
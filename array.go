@@ -0,0 +1,44 @@
+package pg
+
+import (
+	sq "github.com/Masterminds/squirrel"
+)
+
+// WithArrayContains returns a ListOption that filters the rows whose array
+// column contains all the values, i.e. column @> ARRAY[values], e.g.
+// WithArrayContains("tags", "go", "postgres"). The values are bound as a
+// single array parameter, of the type of the column.
+//
+// When no values are given, the returned ListOption is a no-op.
+func WithArrayContains[T any](columnName string, values ...T) ListOption {
+	return withArrayOperator(columnName, "@>", values)
+}
+
+// WithArrayOverlaps works like WithArrayContains, but filters the rows whose
+// array column has any of the values, i.e. column && ARRAY[values].
+func WithArrayOverlaps[T any](columnName string, values ...T) ListOption {
+	return withArrayOperator(columnName, "&&", values)
+}
+
+// WithValueInArrayColumn returns a ListOption that filters the rows whose
+// array column has the value, i.e. value = ANY(column).
+//
+// When the given value is zero, the returned ListOption is a no-op, like With.
+func WithValueInArrayColumn[T any](columnName string, value T) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if isZeroValue(value) {
+			return sb
+		}
+		return sb.Where("? = ANY("+columnName+")", value)
+	})
+}
+
+func withArrayOperator[T any](columnName, op string, values []T) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if len(values) == 0 {
+			return sb
+		}
+		return sb.Where(columnName+" "+op+" ?", values)
+	})
+}
@@ -0,0 +1,61 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// BatchResult is the result of a statement of a Batch.
+type BatchResult struct {
+	RowsAffected int64
+	Err          error
+}
+
+// Batch sends the INSERT/UPDATE/DELETE queries in one batch (one round trip),
+// and returns the results of the statements in the same order as queries. The
+// statements of a batch run in an implicit transaction unless the context
+// carries one (see WithinTx), i.e. a failing statement aborts the following
+// ones, which fail too. The returned error is of sending the batch.
+//
+// The batch is sent as a whole, thus it doesn't flow through the middlewares
+// registered by Use.
+//
+// Example:
+//
+//	results, err := pg.Batch(ctx,
+//		pg.SQL.Update("users").Set("last_seen_at", sq.Expr("now()")).Where(sq.Eq{"id": 1}),
+//		pg.SQL.Insert("audit_logs").Columns("user_id", "action").Values(1, "login"),
+//	)
+func Batch(ctx context.Context, queries ...sq.Sqlizer) ([]BatchResult, error) {
+	batch := new(pgx.Batch)
+	for i, query := range queries {
+		sqlstr, args, err := query.ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("assemble query #%d: %w", i, err)
+		}
+		batch.Queue(sqlstr, args...)
+	}
+
+	results, release, err := sendBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer results.Close()
+
+	out := make([]BatchResult, len(queries))
+	failed := false
+	for i := range queries {
+		tag, err := results.Exec()
+		out[i] = BatchResult{RowsAffected: tag.RowsAffected(), Err: err}
+		failed = failed || err != nil
+	}
+	// The error of a statement is reported by Close again.
+	if err := results.Close(); err != nil && !failed {
+		return out, err
+	}
+	return out, nil
+}
@@ -0,0 +1,86 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// copier is implemented by the queriers able to COPY, e.g. *pgx.Conn and
+// pgx.Tx.
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// BulkInsert loads the structs into the table with the COPY protocol, which is
+// much faster than a multi-row INSERT for large imports, and has no limit of
+// bind parameters. The columns are mapped from the fields as Insert does, all
+// the rows must have the same columns. Returns the number of the inserted
+// rows.
+//
+// The table name is parsed as QuoteIdent does, e.g. it can be made by Ident.
+// The COPY doesn't flow through the middlewares registered by Use.
+//
+// Example:
+//
+//	n, err := pg.BulkInsert(ctx, "events", events)
+func BulkInsert[T any](ctx context.Context, table string, rows []T) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	columns, _, err := structColumns(rows[0], false)
+	if err != nil {
+		return 0, err
+	}
+	values := make([][]any, len(rows))
+	for i, row := range rows {
+		cols, vals, err := structColumns(row, false)
+		if err != nil {
+			return 0, err
+		}
+		if strings.Join(cols, ",") != strings.Join(columns, ",") {
+			return 0, fmt.Errorf("row #%d has different columns than the first row", i)
+		}
+		values[i] = vals
+	}
+
+	tableName, _, ok := splitIdent(table)
+	if !ok {
+		return 0, fmt.Errorf("invalid table name %q", table)
+	}
+
+	c, release, err := copierFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	n, err := c.CopyFrom(ctx, pgx.Identifier(tableName), columns, pgx.CopyFromRows(values))
+	if err != nil {
+		return 0, fmt.Errorf("copy into %s: %w", table, err)
+	}
+	return n, nil
+}
+
+// copierFrom returns the copier of the context, like querier does.
+func copierFrom(ctx context.Context) (copier, func(), error) {
+	if scope := scopeFrom(ctx); scope != nil {
+		return scope.tx, func() {}, nil
+	}
+	if q := querierFrom(ctx); q != nil {
+		c, ok := q.(copier)
+		if !ok {
+			return nil, nil, fmt.Errorf("querier %T can't copy", q)
+		}
+		return c, func() {}, nil
+	}
+
+	conn, err := acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.Release, nil
+}
@@ -0,0 +1,136 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// cacheMaxEntries is the max number of the results cached by GetCached and
+// CachedList.
+const cacheMaxEntries = 10000
+
+// Cached is a result of GetCached or CachedList. It's shared by the callers
+// of the same query, thus must not be modified.
+type Cached[T any] struct {
+	Value    T         `json:"value"`
+	Stale    bool      `json:"stale"` // served after the database failed, see SetStaleFallback
+	CachedAt time.Time `json:"cached_at"`
+}
+
+type cacheEntry struct {
+	value     any
+	cachedAt  time.Time
+	expiresAt time.Time // including the stale fallback
+}
+
+var queryCache = struct {
+	sync.Mutex
+	entries  map[string]cacheEntry
+	maxStale time.Duration
+}{entries: make(map[string]cacheEntry)}
+
+// SetStaleFallback opts in the stale fallback of GetCached and CachedList:
+// when the database is unavailable (see IsUnavailable), the results cached
+// at most maxStale ago are served flagged Stale instead of failing, e.g. for
+// the read-mostly public endpoints during an incident. Zero (default)
+// disables it. Call it at initialization.
+func SetStaleFallback(maxStale time.Duration) {
+	queryCache.Lock()
+	defer queryCache.Unlock()
+	queryCache.maxStale = maxStale
+}
+
+// GetCached works like Get, but caches the row (or nil if not found) for ttl,
+// keyed by the SQL and the args of the query.
+//
+// Example:
+//
+//	query := pg.SQL.Select("*").From("products").Where(sq.Eq{"slug": slug})
+//	product, err := pg.GetCached[Product](ctx, query, time.Minute)
+//	if product.Stale {
+//		w.Header().Set("Warning", `110 - "Response is Stale"`)
+//	}
+func GetCached[T any](ctx context.Context, query sq.SelectBuilder, ttl time.Duration) (*Cached[*T], error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return cached(cacheKey("get", sqlstr, args), ttl, func() (*T, error) {
+		return Get(ctx, new(T), query)
+	})
+}
+
+// CachedList works like ListItems, but caches the page for ttl, keyed by the
+// SQL and the args of the query with the options applied.
+//
+// Example:
+//
+//	result, err := pg.CachedList[*Product](ctx, pg.SQL.Select("*").From("products"), time.Minute, pg.WithOffsetPagination(page))
+func CachedList[T any](ctx context.Context, query sq.SelectBuilder, ttl time.Duration, opts ...ListOption) (*Cached[*ListResult[T]], error) {
+	keyQuery := query
+	for _, opt := range withDefaultListOptions(opts) {
+		keyQuery = opt.Apply(keyQuery)
+	}
+	sqlstr, args, err := keyQuery.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+	return cached(cacheKey("list", sqlstr, args), ttl, func() (*ListResult[T], error) {
+		return ListItems[T](ctx, query, opts...)
+	})
+}
+
+func cacheKey(kind, sqlstr string, args []any) string {
+	return fmt.Sprintf("%s:%s:%#v", kind, sqlstr, args)
+}
+
+// cached returns the cached value of the key if fresh, otherwise fetches and
+// caches it, falling back to the stale value if the database is unavailable.
+func cached[T any](key string, ttl time.Duration, fetch func() (T, error)) (*Cached[T], error) {
+	now := time.Now()
+	queryCache.Lock()
+	entry, ok := queryCache.entries[key]
+	maxStale := queryCache.maxStale
+	queryCache.Unlock()
+	if ok && now.Sub(entry.cachedAt) < ttl {
+		return &Cached[T]{Value: entry.value.(T), CachedAt: entry.cachedAt}, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		if ok && maxStale > 0 && now.Sub(entry.cachedAt) <= maxStale && IsUnavailable(err) {
+			return &Cached[T]{Value: entry.value.(T), Stale: true, CachedAt: entry.cachedAt}, nil
+		}
+		return nil, err
+	}
+
+	now = time.Now()
+	expiresAt := now.Add(ttl)
+	if stale := now.Add(maxStale); stale.After(expiresAt) {
+		expiresAt = stale
+	}
+	queryCache.Lock()
+	if len(queryCache.entries) >= cacheMaxEntries {
+		evictExpired(now)
+	}
+	queryCache.entries[key] = cacheEntry{value: value, cachedAt: now, expiresAt: expiresAt}
+	queryCache.Unlock()
+	return &Cached[T]{Value: value, CachedAt: now}, nil
+}
+
+// evictExpired evicts the expired entries of the cache, or all of them if
+// none is expired. The cache must be locked.
+func evictExpired(now time.Time) {
+	for key, entry := range queryCache.entries {
+		if now.After(entry.expiresAt) {
+			delete(queryCache.entries, key)
+		}
+	}
+	if len(queryCache.entries) >= cacheMaxEntries {
+		queryCache.entries = make(map[string]cacheEntry)
+	}
+}
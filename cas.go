@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// The columns of the configuration-style rows updated by CASUpdate, i.e. the
+// value (jsonb) of the row identified by the id, and its version (bigint)
+// bumped by each update.
+const (
+	CASIDColumn      = "id"
+	CASValueColumn   = "value"
+	CASVersionColumn = "version"
+)
+
+// ErrConflict is returned by CASUpdate when the row has changed since it was
+// read, or doesn't exist.
+var ErrConflict = errors.New("pg: row changed concurrently")
+
+// CASUpdate sets the value (encoded as JSON) of the row identified by id if
+// it's unchanged since it was read (check-and-set), which is either:
+//
+//   - a version (int64), compared with the version column, which is bumped;
+//   - a hash (string), compared with md5(value::text), for the tables without
+//     a version column.
+//
+// Returns ErrConflict if the row has changed, or doesn't exist. It's a
+// lighter alternative to the optimistic locking of the whole model on the
+// configuration tables.
+//
+// Example:
+//
+//	type Config struct {
+//		Value   pg.JSONB[Settings]
+//		Version int64
+//	}
+//
+//	cfg, err := pg.Get(ctx, new(Config), pg.SQL.Select("value", "version").From("settings").Where(sq.Eq{"id": "billing"}))
+//	cfg.Value.V.Retries = 5
+//	if err := pg.CASUpdate(ctx, "settings", "billing", cfg.Version, cfg.Value.V); errors.Is(err, pg.ErrConflict) {
+//		// reload and retry
+//	}
+func CASUpdate(ctx context.Context, table string, id any, expected any, newValue any) error {
+	query := SQL.Update(QuoteIdent(table)).
+		Set(CASValueColumn, JSONB[any]{V: newValue}).
+		Where(sq.Eq{CASIDColumn: id})
+	switch expected := expected.(type) {
+	case string:
+		query = query.Where(fmt.Sprintf("md5(%s::text) = ?", CASValueColumn), expected)
+	case int, int32, int64:
+		query = query.
+			Set(CASVersionColumn, sq.Expr(CASVersionColumn+" + 1")).
+			Where(sq.Eq{CASVersionColumn: expected})
+	default:
+		return fmt.Errorf("expect a version (int64) or a hash (string), got %T", expected)
+	}
+
+	n, err := Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", table, err)
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
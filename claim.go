@@ -0,0 +1,75 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// ClaimRows claims up to n rows matched by the query, which are selected FOR
+// UPDATE SKIP LOCKED, i.e. rows being claimed by other workers are skipped.
+// The claiming update built by markClaimed is applied to the selected rows in
+// the same statement (thus the same transaction), and the claimed rows are
+// returned (RETURNING *).
+//
+// The query must select from a single table without joins, the builder passed
+// to markClaimed is already bound to that table.
+//
+// Usage: the core primitive for schedulers and batch processors.
+//
+// Example:
+//
+//	query := pg.SQL.Select("*").From("jobs").Where(sq.Eq{"status": "pending"}).OrderBy("id")
+//	jobs, err := pg.ClaimRows[Job](ctx, query, 10, func(ub sq.UpdateBuilder) sq.UpdateBuilder {
+//		return ub.Set("status", "running").Set("claimed_at", sq.Expr("now()"))
+//	})
+func ClaimRows[T any](ctx context.Context, query sq.SelectBuilder, n uint64, markClaimed func(sq.UpdateBuilder) sq.UpdateBuilder) ([]T, error) {
+	table, err := selectFromTable(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subquery keeps "?" placeholders, they are numbered by the outer query.
+	candidates := builder.Delete(query, "Columns").(sq.SelectBuilder).
+		PlaceholderFormat(sq.Question).
+		Columns("ctid").
+		Limit(n).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	claim := markClaimed(SQL.Update(table)).
+		Where(sq.Expr("ctid = ANY(ARRAY(?))", candidates)).
+		Suffix("RETURNING *")
+
+	sqlstr, args, err := claim.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble claim query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var claimed []T
+	if err := scanAPI(&claimed).Select(ctx, q, &claimed, sqlstr, args...); err != nil {
+		return nil, fmt.Errorf("claim rows: %w", err)
+	}
+	return claimed, nil
+}
+
+// selectFromTable returns the table name of the FROM clause of the query.
+func selectFromTable(query sq.SelectBuilder) (string, error) {
+	from, ok := builder.Get(query, "From")
+	if !ok || from == nil {
+		return "", errors.New("query has no FROM clause")
+	}
+	sqlstr, _, err := from.(sq.Sqlizer).ToSql()
+	if err != nil {
+		return "", fmt.Errorf("assemble FROM clause: %w", err)
+	}
+	return strings.TrimSpace(sqlstr), nil
+}
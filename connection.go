@@ -0,0 +1,213 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// defaultConnectionSize is the page size of NewConnection without first or
+// last.
+const defaultConnectionSize = 10
+
+// ConnectionArgs is the arguments of a GraphQL Relay connection field, see
+// NewConnection.
+type ConnectionArgs struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// Edge is an edge of a Connection, the node and its cursor.
+type Edge[T any] struct {
+	Node   T      `json:"node"`
+	Cursor string `json:"cursor"`
+}
+
+// PageInfo is the page info of a Connection.
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+// Connection is a page of a GraphQL Relay connection.
+// See https://relay.dev/graphql/connections.htm
+type Connection[T any] struct {
+	Edges    []Edge[T] `json:"edges"`
+	PageInfo PageInfo  `json:"pageInfo"`
+}
+
+// NewConnection builds the Connection of the arguments from the query. It
+// works like SeekList, but lists the page of the Relay connection, i.e. the
+// first nodes after the cursor After, or the last ones before the cursor
+// Before, each with its own cursor. Without First and Last, the first 10
+// nodes are listed. As SeekList, the key columns must identify the rows
+// uniquely, be selected by the query, and be prefixed with "-" to list in
+// descending order.
+//
+// The pages are capped by SetDefaultMaxPageSize. HasPreviousPage is only
+// known to be true with After, and HasNextPage with Before, in the other
+// cases they tell whether there're more nodes on the other end of the page.
+//
+// Example:
+//
+//	func (r *queryResolver) Users(ctx context.Context, first *int, after *string, last *int, before *string) (*pg.Connection[*User], error) {
+//		return pg.NewConnection[*User](ctx, pg.SQL.Select("*").From("users"),
+//			pg.ConnectionArgs{First: first, After: after, Last: last, Before: before}, "-created_at", "-id")
+//	}
+func NewConnection[T any](ctx context.Context, query sq.SelectBuilder, args ConnectionArgs, keyColumns ...string) (*Connection[T], error) {
+	if len(keyColumns) == 0 {
+		return nil, errors.New("no key columns")
+	}
+	columns, desc, err := seekColumns(keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	first, err := connectionSize(args.First, "first")
+	if err != nil {
+		return nil, err
+	}
+	last, err := connectionSize(args.Last, "last")
+	if err != nil {
+		return nil, err
+	}
+	if first < 0 && last < 0 {
+		first = defaultConnectionSize
+	}
+
+	for _, bound := range []struct {
+		cursor *string
+		after  bool
+	}{{args.After, true}, {args.Before, false}} {
+		if bound.cursor == nil || *bound.cursor == "" {
+			continue
+		}
+		values, err := decodeCursorValues(*bound.cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("%w: expect %d values, got %d", ErrInvalidCursor, len(columns), len(values))
+		}
+		// The nodes before the cursor are the ones after it in the reverse
+		// order.
+		reverse := desc
+		if !bound.after {
+			reverse = !desc
+		}
+		query = query.Where(seekCondition(columns, values, reverse))
+	}
+
+	// Take the last nodes by listing in the reverse order, unless taking the
+	// first ones too.
+	backward := first < 0
+	limit := first
+	if backward {
+		limit = last
+	}
+	for _, col := range columns {
+		if desc != backward {
+			query = query.OrderBy(col + " DESC")
+		} else {
+			query = query.OrderBy(col)
+		}
+	}
+	query = query.Limit(uint64(limit) + 1)
+
+	edges, err := listEdges[T](ctx, query, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := new(Connection[T])
+	more := len(edges) > limit
+	if more {
+		edges = edges[:limit]
+	}
+	if backward {
+		for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+			edges[i], edges[j] = edges[j], edges[i]
+		}
+		conn.PageInfo.HasPreviousPage = more
+		conn.PageInfo.HasNextPage = args.Before != nil && *args.Before != ""
+	} else {
+		conn.PageInfo.HasNextPage = more
+		conn.PageInfo.HasPreviousPage = args.After != nil && *args.After != ""
+		if last >= 0 && len(edges) > last {
+			edges = edges[len(edges)-last:]
+			conn.PageInfo.HasPreviousPage = true
+		}
+	}
+
+	conn.Edges = edges
+	if len(edges) > 0 {
+		conn.PageInfo.StartCursor = &edges[0].Cursor
+		conn.PageInfo.EndCursor = &edges[len(edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+// connectionSize validates the first or last argument, -1 if absent. It's
+// capped by the default max page size.
+func connectionSize(size *int, name string) (int, error) {
+	if size == nil {
+		return -1, nil
+	}
+	if *size < 0 {
+		return 0, fmt.Errorf("%w: %s %d", ErrInvalidLimit, name, *size)
+	}
+	if defaultMaxPageSize > 0 && int64(*size) > defaultMaxPageSize {
+		return int(defaultMaxPageSize), nil
+	}
+	return *size, nil
+}
+
+// listEdges runs the query, scanning the rows into the nodes of the edges
+// along with the cursors of their key columns.
+func listEdges[T any](ctx context.Context, query sq.SelectBuilder, columns []string) ([]Edge[T], error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fieldIndexes, err := seekFieldIndexes(rows.FieldDescriptions(), columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []Edge[T]
+	scanner := scanAPI(new(T)).NewRowScanner(rows)
+	for rows.Next() {
+		node, err := scanRow[T](scanner)
+		if err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		edge := Edge[T]{Node: node}
+		keys, err := seekKeys(rows, fieldIndexes)
+		if err != nil {
+			return nil, err
+		}
+		if edge.Cursor, err = EncodeCursor(keys...); err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+	return edges, rows.Err()
+}
@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConsistencyToken returns the current WAL position (LSN) of the primary, to
+// be captured after a write. A replica which has replayed up to the position
+// sees the write, see WaitForLSN. If the context carries a transaction (see
+// WithinTx), capture the token after it commits, e.g. in AfterCommit.
+//
+// Example:
+//
+//	_, err := pg.Exec(ctx, query)
+//	token, err := pg.ConsistencyToken(ctx) // e.g. set as a cookie
+func ConsistencyToken(ctx context.Context) (string, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	var lsn string
+	if err := q.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return "", fmt.Errorf("get current wal lsn: %w", err)
+	}
+	return lsn, nil
+}
+
+// WaitForLSN waits until the replica has replayed the WAL up to the position
+// of the token (see ConsistencyToken), so that a follow-up read served by the
+// replica sees the write (read-your-writes). It polls the replica until the
+// context is done. An empty token returns immediately.
+//
+// Example:
+//
+//	if err := pg.WaitForLSN(ctx, replica, token); err == nil {
+//		ctx = pg.WithQuerier(ctx, replica)
+//	}
+func WaitForLSN(ctx context.Context, replica Querier, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	interval := 5 * time.Millisecond
+	for {
+		var caughtUp bool
+		err := replica.QueryRow(ctx, "SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, true)", token).Scan(&caughtUp)
+		if err != nil {
+			return fmt.Errorf("check replay lsn: %w", err)
+		}
+		if caughtUp {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if interval < 100*time.Millisecond {
+			interval *= 2
+		}
+	}
+}
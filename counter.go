@@ -0,0 +1,112 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// CountersSchema is the DDL of the table backing Counter. Run it along with
+// the migrations of the application.
+const CountersSchema = `
+CREATE TABLE IF NOT EXISTS counters (
+	name  text NOT NULL,
+	slot  int NOT NULL,
+	value bigint NOT NULL DEFAULT 0,
+	PRIMARY KEY (name, slot)
+);
+`
+
+// counterRollUpQuery folds the values of the slots into the slot 0 of each
+// counter, atomically.
+const counterRollUpQuery = `
+WITH removed AS (
+	DELETE FROM counters WHERE slot > 0 RETURNING name, value
+)
+INSERT INTO counters (name, slot, value)
+SELECT name, 0, SUM(value) FROM removed GROUP BY name
+ON CONFLICT (name, slot) DO UPDATE SET value = counters.value + excluded.value
+`
+
+// Counter is the counter store backed by the counters table (see
+// CountersSchema), with 16 slots per counter.
+//
+// Usage: hot counters like view counts.
+//
+// Example:
+//
+//	go pg.Counter.RunRollUp(ctx, time.Minute)
+//	err := pg.Counter.Incr(ctx, "article:1:views", 1)
+//	views, err := pg.Counter.Get(ctx, "article:1:views")
+var Counter = &CounterStore{Slots: 16}
+
+// CounterStore implements contention-aware counters. Each counter is split
+// into multiple rows (slots), an increment updates a random slot, so that the
+// concurrent increments of a hot counter rarely contend on the same row. The
+// value of a counter is the sum of its slots. RollUp periodically folds the
+// slots into one row to keep the table small.
+type CounterStore struct {
+	Slots int // the number of slots per counter
+}
+
+// Incr increments the counter by delta (negative to decrement).
+func (s *CounterStore) Incr(ctx context.Context, name string, delta int64) error {
+	slots := s.Slots
+	if slots <= 0 {
+		slots = 1
+	}
+	query := SQL.Insert("counters").
+		Columns("name", "slot", "value").
+		Values(name, 1+rand.Intn(slots), delta).
+		Suffix("ON CONFLICT (name, slot) DO UPDATE SET value = counters.value + excluded.value")
+	if _, err := Exec(ctx, query); err != nil {
+		return fmt.Errorf("increment counter %q: %w", name, err)
+	}
+	return nil
+}
+
+// Get returns the value of the counter, zero if it doesn't exist.
+func (s *CounterStore) Get(ctx context.Context, name string) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var value int64
+	err = q.QueryRow(ctx, "SELECT COALESCE(SUM(value), 0)::bigint FROM counters WHERE name = $1", name).Scan(&value)
+	if err != nil {
+		return 0, fmt.Errorf("get counter %q: %w", name, err)
+	}
+	return value, nil
+}
+
+// RollUp folds the slots of all the counters into one row per counter.
+func (s *CounterStore) RollUp(ctx context.Context) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := q.Exec(ctx, counterRollUpQuery); err != nil {
+		return fmt.Errorf("roll up counters: %w", err)
+	}
+	return nil
+}
+
+// RunRollUp runs RollUp at the given interval until the context is done.
+// A failed roll-up is retried on the next tick.
+func (s *CounterStore) RunRollUp(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.RollUp(ctx)
+		}
+	}
+}
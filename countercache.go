@@ -0,0 +1,104 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// CounterCacheSpec maintains a denormalized counter of the parent rows, e.g.
+// posts.comments_count, counting the child rows referencing them, e.g.
+// comments.post_id.
+type CounterCacheSpec struct {
+	ParentTable string
+	ParentKey   string // primary key of the parent table, "id" by default
+	Column      string // the counter column of the parent table
+	ChildTable  string
+	ForeignKey  string // the column of the child table referencing the parent
+}
+
+// CounterCache creates a CounterCacheSpec. The child rows must be inserted
+// and deleted through it to keep the counter consistent.
+//
+// Example:
+//
+//	var commentsCount = pg.CounterCache("posts", "comments_count", "comments", "post_id")
+//	err := commentsCount.Insert(ctx, tx, map[string]any{"post_id": 1, "body": "Nice!"})
+//	n, err := commentsCount.Delete(ctx, tx, sq.Eq{"id": commentID})
+func CounterCache(parentTable, column, childTable, fk string) *CounterCacheSpec {
+	return &CounterCacheSpec{
+		ParentTable: parentTable,
+		ParentKey:   "id",
+		Column:      column,
+		ChildTable:  childTable,
+		ForeignKey:  fk,
+	}
+}
+
+// Insert inserts a child row and increments the counter of its parent in the
+// same statement, using q (e.g. the transaction of the surrounding changes).
+func (c *CounterCacheSpec) Insert(ctx context.Context, q Querier, row map[string]any) error {
+	insert := sq.Insert(QuoteIdent(c.ChildTable)).SetMap(row).Suffix("RETURNING " + c.ForeignKey)
+	if _, err := c.mutate(ctx, q, insert, "+"); err != nil {
+		return fmt.Errorf("insert %s: %w", c.ChildTable, err)
+	}
+	return nil
+}
+
+// Delete deletes the child rows matching the condition and decrements the
+// counters of their parents in the same statement, using q (e.g. the
+// transaction of the surrounding changes). Returns the number of the deleted
+// rows.
+func (c *CounterCacheSpec) Delete(ctx context.Context, q Querier, where sq.Sqlizer) (int64, error) {
+	del := sq.Delete(QuoteIdent(c.ChildTable)).Where(where).Suffix("RETURNING " + c.ForeignKey)
+	n, err := c.mutate(ctx, q, del, "-")
+	if err != nil {
+		return 0, fmt.Errorf("delete %s: %w", c.ChildTable, err)
+	}
+	return n, nil
+}
+
+// mutate runs the child mutation (returning the foreign keys) in a CTE, and
+// adjusts the counters of the affected parents. Returns the count of the
+// mutated child rows.
+func (c *CounterCacheSpec) mutate(ctx context.Context, q Querier, mutation sq.Sqlizer, op string) (int64, error) {
+	sqlstr, args, err := mutation.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble query: %w", err)
+	}
+	sqlstr = fmt.Sprintf(`WITH mutated AS (%s),
+counted AS (SELECT %s AS key, COUNT(*) AS n FROM mutated GROUP BY %s),
+updated AS (
+	UPDATE %s AS p SET %s = p.%s %s counted.n FROM counted WHERE p.%s = counted.key RETURNING 1
+)
+SELECT COALESCE(SUM(n), 0)::bigint FROM counted`,
+		sqlstr, c.ForeignKey, c.ForeignKey,
+		QuoteIdent(c.ParentTable), c.Column, c.Column, op, c.parentKey(),
+	)
+	if sqlstr, err = sq.Dollar.ReplacePlaceholders(sqlstr); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err := q.QueryRow(ctx, sqlstr, args...).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Recount recomputes all the counters from the child rows, e.g. to repair the
+// counters after mutations bypassing the counter cache.
+func (c *CounterCacheSpec) Recount(ctx context.Context) (int64, error) {
+	query := SQL.Update(QuoteIdent(c.ParentTable)+" AS p").Set(c.Column, sq.Expr(fmt.Sprintf(
+		"(SELECT COUNT(*) FROM %s AS c WHERE c.%s = p.%s)", QuoteIdent(c.ChildTable), c.ForeignKey, c.parentKey(),
+	)))
+	return Exec(ctx, query)
+}
+
+func (c *CounterCacheSpec) parentKey() string {
+	if c.ParentKey == "" {
+		return "id"
+	}
+	return c.ParentKey
+}
@@ -0,0 +1,176 @@
+package pg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed standard 5-field cron expression:
+// minute hour day-of-month month day-of-week.
+type cronSpec struct {
+	minute  [60]bool
+	hour    [24]bool
+	dom     [32]bool
+	month   [13]bool
+	dow     [7]bool
+	domStar bool
+	dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	cronDayNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+)
+
+// parseCron parses a cron expression. Supports "*", lists ("1,2"), ranges
+// ("1-5"), steps ("*/15", "1-30/5"), the names of the months and the days of
+// the week ("JAN", "MON-FRI", case-insensitive) and the macros like "@daily".
+func parseCron(expr string) (*cronSpec, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	spec := new(cronSpec)
+	parts := []struct {
+		set      []bool
+		min, max int
+		names    []string
+	}{
+		{spec.minute[:], 0, 59, nil},
+		{spec.hour[:], 0, 23, nil},
+		{spec.dom[:], 1, 31, nil},
+		{spec.month[:], 1, 12, cronMonthNames},
+		{spec.dow[:], 0, 7, cronDayNames},
+	}
+	for i, part := range parts {
+		if err := parseCronField(fields[i], part.set, part.min, part.max, part.names); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	spec.domStar = fields[2] == "*"
+	spec.dowStar = fields[4] == "*"
+	return spec, nil
+}
+
+func parseCronField(field string, set []bool, min, max int, names []string) error {
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in %q", item)
+			}
+			step = n
+			item = item[:i]
+		}
+
+		lo, hi := min, max
+		if item != "*" {
+			var err error
+			bounds := strings.SplitN(item, "-", 2)
+			if lo, err = parseCronValue(bounds[0], min, names); err != nil {
+				return fmt.Errorf("invalid value %q", item)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = parseCronValue(bounds[1], min, names); err != nil {
+					return fmt.Errorf("invalid value %q", item)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("value out of range [%d, %d] in %q", min, max, item)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v%len(set)] = true // day-of-week 7 is Sunday as 0
+		}
+	}
+	return nil
+}
+
+// parseCronValue parses a number, or a name of the names which are numbered
+// from min.
+func parseCronValue(s string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	return strconv.Atoi(s)
+}
+
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[t.Weekday()]
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the earliest time after t matching the spec, in the wall clock
+// of the location of t. A time repeated by a DST fall-back matches only once,
+// and a time skipped by a DST spring-forward matches as shifted by the gap,
+// e.g. 02:30 as 03:30. Returns the zero time if no match within 5 years.
+func (s *cronSpec) next(t time.Time) time.Time {
+	// The wall clock is computed in UTC, which has no DST.
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	limit := wall.AddDate(5, 0, 0)
+	for wall = s.nextWall(wall.Add(time.Minute), limit); !wall.IsZero(); wall = s.nextWall(wall.Add(time.Minute), limit) {
+		y, m, d := wall.Date()
+		next := time.Date(y, m, d, wall.Hour(), wall.Minute(), 0, 0, t.Location())
+		ny, nm, nd := next.Date()
+		if shift := wall.Sub(time.Date(ny, nm, nd, next.Hour(), next.Minute(), 0, 0, time.UTC)); shift > 0 {
+			// In a DST gap, normalized backward by time.Date.
+			next = next.Add(shift)
+		}
+		if next.After(t) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// nextWall returns the earliest wall clock (in UTC) from t matching the spec,
+// or the zero time if no match before limit.
+func (s *cronSpec) nextWall(t, limit time.Time) time.Time {
+	for t.Before(limit) {
+		y, m, d := t.Date()
+		if !s.month[m] {
+			t = time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.hour[t.Hour()] {
+			t = time.Date(y, m, d, t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
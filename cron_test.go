@@ -0,0 +1,141 @@
+package pg
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"* * * JANUARY *",
+		"* * * * MON-",
+		"@every 5m",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		from string
+		want string // empty for no match
+	}{
+		// fields
+		{"*/15 * * * *", "2024-01-01T10:07:30Z", "2024-01-01T10:15:00Z"},
+		{"*/15 * * * *", "2024-01-01T10:45:00Z", "2024-01-01T11:00:00Z"},
+		{"5,10 8-9 * * *", "2024-01-01T09:10:00Z", "2024-01-02T08:05:00Z"},
+		{"10-30/10 * * * *", "2024-01-01T10:21:00Z", "2024-01-01T10:30:00Z"},
+		{"5/20 * * * *", "2024-01-01T10:30:00Z", "2024-01-01T10:45:00Z"},
+		{"0 9 * * MON-FRI", "2024-01-05T09:00:00Z", "2024-01-08T09:00:00Z"},
+		{"0 9 * * sat,7", "2024-01-01T00:00:00Z", "2024-01-06T09:00:00Z"},
+		{"0 0 1 feb *", "2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z"},
+		{"@hourly", "2024-01-01T10:00:00Z", "2024-01-01T11:00:00Z"},
+		{"@weekly", "2024-01-01T00:00:00Z", "2024-01-07T00:00:00Z"},
+		// day-of-month OR day-of-week when both are restricted
+		{"0 0 13 * 5", "2024-01-01T00:00:00Z", "2024-01-05T00:00:00Z"},
+		{"0 0 13 * 5", "2024-01-12T00:00:00Z", "2024-01-13T00:00:00Z"},
+		// day-of-month AND day-of-week when either is "*"
+		{"0 0 * * 5", "2024-01-01T00:00:00Z", "2024-01-05T00:00:00Z"},
+		// rollover of the hour, day, month and year
+		{"0 * * * *", "2024-01-31T23:59:00Z", "2024-02-01T00:00:00Z"},
+		{"0 0 31 * *", "2024-01-31T00:00:00Z", "2024-03-31T00:00:00Z"},
+		{"0 0 1 1 *", "2024-06-15T12:00:00Z", "2025-01-01T00:00:00Z"},
+		{"0 0 29 2 *", "2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 0 30 2 *", "2024-01-01T00:00:00Z", ""},
+	} {
+		from, _ := time.Parse(time.RFC3339, tc.from)
+		spec, err := parseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tc.expr, err)
+		}
+		got := spec.next(from)
+		if tc.want == "" {
+			if !got.IsZero() {
+				t.Errorf("next of %q from %s = %s, want no match", tc.expr, tc.from, got)
+			}
+			continue
+		}
+		if want, _ := time.Parse(time.RFC3339, tc.want); !got.Equal(want) {
+			t.Errorf("next of %q from %s = %s, want %s", tc.expr, tc.from, got.Format(time.RFC3339), tc.want)
+		}
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		expr string
+		from string
+		want []string // the following ticks
+	}{
+		{
+			// 2024-03-10 02:00 EST jumps to 03:00 EDT.
+			name: "spring forward",
+			expr: "30 2 * * *",
+			from: "2024-03-09T03:00:00-05:00",
+			want: []string{"2024-03-10T03:30:00-04:00", "2024-03-11T02:30:00-04:00"},
+		},
+		{
+			name: "spring forward hourly",
+			expr: "0 * * * *",
+			from: "2024-03-10T00:30:00-05:00",
+			want: []string{"2024-03-10T01:00:00-05:00", "2024-03-10T03:00:00-04:00", "2024-03-10T04:00:00-04:00"},
+		},
+		{
+			// 2024-11-03 02:00 EDT falls back to 01:00 EST.
+			name: "fall back",
+			expr: "30 1 * * *",
+			from: "2024-11-03T00:00:00-04:00",
+			want: []string{"2024-11-03T01:30:00-04:00", "2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "fall back from the repeated hour",
+			expr: "30 1 * * *",
+			from: "2024-11-03T01:10:00-05:00",
+			want: []string{"2024-11-04T01:30:00-05:00"},
+		},
+		{
+			name: "fall back hourly",
+			expr: "0 * * * *",
+			from: "2024-11-03T00:30:00-04:00",
+			want: []string{"2024-11-03T01:00:00-04:00", "2024-11-03T02:00:00-05:00"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec, err := parseCron(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, _ := time.Parse(time.RFC3339, tc.from)
+			tick := from.In(loc)
+			for _, want := range tc.want {
+				tick = spec.next(tick)
+				if w, _ := time.Parse(time.RFC3339, want); !tick.Equal(w) {
+					t.Fatalf("next of %q = %s, want %s", tc.expr, tick.Format(time.RFC3339), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package pg
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed, or its signature
+// doesn't match (see SetCursorSecret).
+var ErrInvalidCursor = errors.New("pg: invalid cursor")
+
+var cursorSecret []byte
+
+// SetCursorSecret sets the secret to sign the cursors with (HMAC-SHA256), so
+// that the clients can't forge or tamper with them. The unsigned cursors, or
+// the ones signed with another secret, are rejected with ErrInvalidCursor.
+// Nil (default) means the cursors are not signed. Call it at initialization.
+func SetCursorSecret(secret []byte) {
+	cursorSecret = secret
+}
+
+// EncodeCursor encodes the keyset values, e.g. created_at and id of the last
+// row of the page, into an opaque URL-safe token. The values are encoded as
+// JSON and signed if a secret is set by SetCursorSecret.
+//
+// Example:
+//
+//	cursor, err := pg.EncodeCursor(last.CreatedAt, last.ID)
+func EncodeCursor(values ...any) (string, error) {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(raw)
+	if cursorSecret != nil {
+		cursor += "." + base64.RawURLEncoding.EncodeToString(signCursor(raw))
+	}
+	return cursor, nil
+}
+
+// DecodeCursor decodes a token made by EncodeCursor into dest, which are the
+// pointers to the keyset values, in the same order as they were encoded.
+//
+// Example:
+//
+//	var createdAt time.Time
+//	var id int64
+//	err := pg.DecodeCursor(cursor, &createdAt, &id)
+func DecodeCursor(cursor string, dest ...any) error {
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+	if len(values) != len(dest) {
+		return fmt.Errorf("%w: expect %d values, got %d", ErrInvalidCursor, len(dest), len(values))
+	}
+	for i, value := range values {
+		if err := json.Unmarshal(value, dest[i]); err != nil {
+			return fmt.Errorf("%w: value #%d: %v", ErrInvalidCursor, i, err)
+		}
+	}
+	return nil
+}
+
+// decodeCursor verifies the signature of the cursor, and splits it into the
+// encoded values.
+func decodeCursor(cursor string) ([]json.RawMessage, error) {
+	payload, signature, signed := strings.Cut(cursor, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursorSecret != nil {
+		mac, err := base64.RawURLEncoding.DecodeString(signature)
+		if !signed || err != nil || !hmac.Equal(mac, signCursor(raw)) {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return values, nil
+}
+
+// decodeCursorValues decodes the values of the cursor as query arguments. The
+// numbers are decoded as json.Number to keep their precision.
+func decodeCursorValues(cursor string) ([]any, error) {
+	raws, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(raws))
+	for i, raw := range raws {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&values[i]); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+func signCursor(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,114 @@
+package pg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withCursorSecret(t *testing.T, secret []byte) {
+	t.Helper()
+	SetCursorSecret(secret)
+	t.Cleanup(func() { SetCursorSecret(nil) })
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, secret := range [][]byte{nil, []byte("s3cret")} {
+		withCursorSecret(t, secret)
+
+		createdAt := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+		cursor, err := EncodeCursor(createdAt, int64(42), "x.y")
+		if err != nil {
+			t.Fatalf("EncodeCursor: %v", err)
+		}
+		if signed := strings.Contains(cursor, "."); signed != (secret != nil) {
+			t.Errorf("cursor %q signed = %v, want %v", cursor, signed, secret != nil)
+		}
+
+		var (
+			gotCreatedAt time.Time
+			gotID        int64
+			gotName      string
+		)
+		if err := DecodeCursor(cursor, &gotCreatedAt, &gotID, &gotName); err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		if !gotCreatedAt.Equal(createdAt) || gotID != 42 || gotName != "x.y" {
+			t.Errorf("DecodeCursor = %v, %d, %q", gotCreatedAt, gotID, gotName)
+		}
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	withCursorSecret(t, []byte("s3cret"))
+	cursor, err := EncodeCursor(int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(cursor, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("[2]"))
+
+	SetCursorSecret([]byte("another"))
+	signedByAnother, err := EncodeCursor(int64(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetCursorSecret([]byte("s3cret"))
+
+	for _, tc := range []struct {
+		name   string
+		cursor string
+	}{
+		{"tampered payload", forged + "." + signature},
+		{"tampered signature", payload + "." + strings.Repeat("A", len(signature))},
+		{"malformed signature", payload + ".!!"},
+		{"unsigned", payload},
+		{"wrong key", signedByAnother},
+		{"malformed payload", "!!." + signature},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("{"))},
+		{"empty", ""},
+	} {
+		var id int64
+		if err := DecodeCursor(tc.cursor, &id); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: DecodeCursor(%q) = %v, want ErrInvalidCursor", tc.name, tc.cursor, err)
+		}
+		if _, err := decodeCursorValues(tc.cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: decodeCursorValues(%q) = %v, want ErrInvalidCursor", tc.name, tc.cursor, err)
+		}
+	}
+}
+
+func TestCursorValueCount(t *testing.T) {
+	cursor, err := EncodeCursor(int64(1), int64(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id int64
+	if err := DecodeCursor(cursor, &id); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("DecodeCursor with fewer dest = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestCursorInt64Precision(t *testing.T) {
+	for _, n := range []int64{math.MaxInt64, math.MinInt64, 1<<53 + 1} {
+		cursor, err := EncodeCursor(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values, err := decodeCursorValues(cursor)
+		if err != nil {
+			t.Fatalf("decodeCursorValues: %v", err)
+		}
+		number, ok := values[0].(json.Number)
+		if !ok {
+			t.Fatalf("value = %#v, want a json.Number", values[0])
+		}
+		if got, err := number.Int64(); err != nil || got != n {
+			t.Errorf("value = %v (%v), want %d", number, err, n)
+		}
+	}
+}
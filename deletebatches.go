@@ -0,0 +1,68 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// DeleteInBatches deletes the rows of the DELETE query in batches of at most
+// batchSize rows, each a statement of its own, thus committed on its own,
+// pausing between the batches, so that deleting a big set of rows never holds
+// the locks for long nor spikes the WAL like a single huge DELETE does. The
+// rows of a batch are picked by their physical location, i.e. (tableoid,
+// ctid) IN (SELECT tableoid, ctid ... LIMIT batchSize). progress (optional) is
+// called with the number of the rows deleted so far after each batch.
+// Returns the number of the deleted rows.
+//
+// It must not be called within a transaction (see WithinTx), which would
+// defeat the batching.
+//
+// Example:
+//
+//	query := pg.SQL.Delete("events").Where("created_at < now() - interval '90 days'")
+//	n, err := pg.DeleteInBatches(ctx, query, 5000, 100*time.Millisecond, func(deleted int64) {
+//		log.Printf("deleted %d events", deleted)
+//	})
+func DeleteInBatches(ctx context.Context, deleteQuery sq.DeleteBuilder, batchSize uint64, pause time.Duration, progress func(deleted int64)) (int64, error) {
+	if scopeFrom(ctx) != nil {
+		return 0, errors.New("can't delete in batches within a transaction")
+	}
+	if batchSize == 0 {
+		return 0, errors.New("invalid batch size 0")
+	}
+	from, _ := builder.Get(deleteQuery, "From")
+	table := fmt.Sprint(from)
+	batch := sq.Select("tableoid", "ctid").From(table).Limit(batchSize)
+	if parts, ok := builder.Get(deleteQuery, "WhereParts"); ok {
+		for _, part := range parts.([]sq.Sqlizer) {
+			batch = batch.Where(part)
+		}
+	}
+	query := SQL.Delete(table).Where(sq.Expr("(tableoid, ctid) IN (?)", batch))
+
+	var deleted int64
+	for {
+		n, err := Exec(ctx, query)
+		if err != nil {
+			return deleted, fmt.Errorf("delete batch from %s: %w", table, err)
+		}
+		deleted += n
+		if progress != nil {
+			progress(deleted)
+		}
+		if uint64(n) < batchSize {
+			return deleted, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return deleted, ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// QueryDescription describes what a List call would do. See Describe.
+type QueryDescription struct {
+	Tables        []string          // the tables in the FROM and JOIN clauses
+	FilterColumns []string          // the columns filtered by the options, e.g. With
+	Sort          []string          // e.g. "created_at desc"
+	Pagination    *OffsetPagination // nil if no pagination option
+	SQL           string            // the composed query
+	Args          []any             // the bind parameters of the query
+}
+
+// Describe composes the query with the options as List does, without
+// executing it, and describes the result, so that the authorization layers and
+// the tests can reason about what the query will do.
+//
+// Example:
+//
+//	desc, err := pg.Describe(query, opts...)
+//	for _, table := range desc.Tables {
+//		if !allowed[table] { ... }
+//	}
+func Describe(query sq.SelectBuilder, opts ...ListOption) (*QueryDescription, error) {
+	filteringOpts, pagingOpts, sortingOpts := CategorizedListOptions(withDefaultListOptions(opts)...)
+	if len(pagingOpts) > 1 {
+		return nil, errors.New("only one pagination option is allowed")
+	}
+
+	desc := new(QueryDescription)
+	for _, ref := range tableRefs(query) {
+		desc.Tables = append(desc.Tables, ref.name)
+	}
+	for _, opt := range filteringOpts {
+		if filter, ok := opt.(*columnFilterOption); ok {
+			desc.FilterColumns = append(desc.FilterColumns, filter.column)
+		}
+		query = opt.Apply(query)
+	}
+	for _, opt := range sortingOpts {
+		for _, sortBy := range sortKeysOf(opt) {
+			if clause, err := sortBy.clause(); err == nil && sortBy.columnName != "" {
+				desc.Sort = append(desc.Sort, clause)
+			}
+		}
+		query = opt.Apply(query)
+	}
+	for _, opt := range pagingOpts {
+		if paging, ok := opt.(*withOffsetPaginationOption); ok {
+			desc.Pagination = paging.page
+		}
+		query = opt.Apply(query)
+	}
+
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+	desc.SQL, desc.Args = sqlstr, args
+	return desc, nil
+}
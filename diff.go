@@ -0,0 +1,141 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryDiff is the difference between the rows of two queries, see
+// DiffQueries. The rows are keyed by the column names.
+type QueryDiff struct {
+	Added   []map[string]any // rows returned by b only
+	Removed []map[string]any // rows returned by a only
+	Changed []RowChange      // rows returned by both, but with different values
+}
+
+// RowChange is a row returned by both queries of DiffQueries with different
+// values.
+type RowChange struct {
+	Key     any
+	Before  map[string]any // the row returned by a
+	After   map[string]any // the row returned by b
+	Columns []string       // the different columns, sorted
+}
+
+// Empty tells whether the queries return the same rows.
+func (d *QueryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffQueries runs both queries and compares their rows matched by the key
+// column, which must be selected by both and identify the rows uniquely.
+// Returns the rows added by b, removed from a and the changed ones, in the
+// order returned by the queries. A column selected by one query only counts
+// as a change.
+//
+// Usage: verifying a rewritten filter, or a new schema against the old one,
+// during refactors and migrations.
+//
+// Example:
+//
+//	diff, err := pg.DiffQueries(ctx,
+//		pg.SQL.Select("id", "status").From("orders").Where("status <> 'archived'"),
+//		pg.SQL.Select("id", "status").From("orders").Where(sq.Eq{"archived_at": nil}),
+//		"id",
+//	)
+//	if err == nil && !diff.Empty() {
+//		log.Printf("added %d, removed %d, changed %d", len(diff.Added), len(diff.Removed), len(diff.Changed))
+//	}
+func DiffQueries(ctx context.Context, a, b sq.SelectBuilder, key string) (*QueryDiff, error) {
+	before, err := diffRows(ctx, a, key)
+	if err != nil {
+		return nil, fmt.Errorf("query a: %w", err)
+	}
+	after, err := diffRows(ctx, b, key)
+	if err != nil {
+		return nil, fmt.Errorf("query b: %w", err)
+	}
+
+	beforeByKey := make(map[string]map[string]any, len(before))
+	for _, row := range before {
+		beforeByKey[fmt.Sprint(row[key])] = row
+	}
+	diff := new(QueryDiff)
+	seen := make(map[string]bool, len(after))
+	for _, row := range after {
+		k := fmt.Sprint(row[key])
+		seen[k] = true
+		old, ok := beforeByKey[k]
+		if !ok {
+			diff.Added = append(diff.Added, row)
+			continue
+		}
+		if columns := changedColumns(old, row); len(columns) > 0 {
+			diff.Changed = append(diff.Changed, RowChange{Key: row[key], Before: old, After: row, Columns: columns})
+		}
+	}
+	for _, row := range before {
+		if !seen[fmt.Sprint(row[key])] {
+			diff.Removed = append(diff.Removed, row)
+		}
+	}
+	return diff, nil
+}
+
+// diffRows runs the query, collecting the rows and checking the key column.
+func diffRows(ctx context.Context, query sq.SelectBuilder, key string) ([]map[string]any, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool, len(result))
+	for _, row := range result {
+		value, ok := row[key]
+		if !ok {
+			return nil, fmt.Errorf("key column %s not selected", key)
+		}
+		k := fmt.Sprint(value)
+		if keys[k] {
+			return nil, fmt.Errorf("duplicate key %s: %v", key, value)
+		}
+		keys[k] = true
+	}
+	return result, nil
+}
+
+func changedColumns(before, after map[string]any) []string {
+	var columns []string
+	for column, value := range before {
+		if other, ok := after[column]; !ok || !reflect.DeepEqual(value, other) {
+			columns = append(columns, column)
+		}
+	}
+	for column := range after {
+		if _, ok := before[column]; !ok {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
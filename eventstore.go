@@ -0,0 +1,192 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// EventsSchema is the DDL of the table backing the event store. Run it along
+// with the migrations of the application. It requires PostgreSQL 13 or later
+// (pg_current_xact_id).
+const EventsSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	id         bigserial PRIMARY KEY,
+	stream     text NOT NULL,
+	version    bigint NOT NULL,
+	type       text NOT NULL,
+	data       jsonb NOT NULL,
+	metadata   jsonb,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	tx_id      bigint NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+	UNIQUE (stream, version)
+);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS tx_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE events ALTER COLUMN tx_id SET DEFAULT pg_current_xact_id()::text::bigint;
+CREATE INDEX IF NOT EXISTS events_tx_id_id_idx ON events (tx_id, id);
+`
+
+// eventColumns is the columns of the events table scanned into an Event. The
+// tx_id column, the transaction appending the event (see Projection), isn't
+// part of the event.
+var eventColumns = []string{"id", "stream", "version", "type", "data", "metadata", "created_at"}
+
+// AnyVersion disables the optimistic concurrency check of EventStore.Append.
+const AnyVersion int64 = -1
+
+// ErrVersionConflict is returned by EventStore.Append when the stream is not
+// at the expected version, i.e. it has been appended concurrently.
+var ErrVersionConflict = errors.New("pg: stream version conflict")
+
+// Event is an event stored in the event store.
+type Event struct {
+	ID        int64           `json:"id"` // global position
+	Stream    string          `json:"stream"`
+	Version   int64           `json:"version"` // position in the stream, starting from 1
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NamingStrategy implements ModelNaming, the columns of the events table are
+// in snake case regardless of the global naming strategy.
+func (Event) NamingStrategy() NamingStrategy { return SnakeCase }
+
+// NewEvent is an event to append to the event store. Data and Metadata are
+// stored as JSON.
+type NewEvent struct {
+	Type     string
+	Data     any
+	Metadata any
+}
+
+// Events is the event store backed by the events table (see EventsSchema).
+//
+// Example:
+//
+//	version, err := pg.Events.Append(ctx, "order-1", 0, pg.NewEvent{Type: "OrderPlaced", Data: order})
+//	events, err := pg.Events.ReadStream(ctx, "order-1", 0, 100)
+var Events = new(EventStore)
+
+// EventStore is an append-only event store. See Events.
+type EventStore struct{}
+
+// Append appends events to the stream, given the version the stream is
+// expected to be at (0 for a new stream, AnyVersion to skip the check).
+// Returns ErrVersionConflict if the stream is at another version. The
+// uniqueness of (stream, version) guarantees optimistic concurrency. Returns
+// the new version of the stream. If the context carries a transaction (see
+// WithinTx), the events are appended in it.
+func (s *EventStore) Append(ctx context.Context, stream string, expectedVersion int64, events ...NewEvent) (version int64, err error) {
+	err = WithinTx(ctx, func(ctx context.Context) error {
+		version, err = s.append(ctx, stream, expectedVersion, events)
+		return err
+	})
+	if IsUniqueViolation(err) {
+		return 0, ErrVersionConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *EventStore) append(ctx context.Context, stream string, expectedVersion int64, events []NewEvent) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var version int64
+	err = q.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM events WHERE stream = $1", stream).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("read stream version: %w", err)
+	}
+	if expectedVersion != AnyVersion && version != expectedVersion {
+		return 0, ErrVersionConflict
+	}
+	if len(events) == 0 {
+		return version, nil
+	}
+
+	query := SQL.Insert("events").Columns("stream", "version", "type", "data", "metadata")
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return 0, fmt.Errorf("marshal event data: %w", err)
+		}
+		var metadata []byte
+		if event.Metadata != nil {
+			if metadata, err = json.Marshal(event.Metadata); err != nil {
+				return 0, fmt.Errorf("marshal event metadata: %w", err)
+			}
+		}
+		version++
+		query = query.Values(stream, version, event.Type, data, metadata)
+	}
+
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble insert query: %w", err)
+	}
+	if _, err := q.Exec(ctx, sqlstr, args...); err != nil {
+		if IsUniqueViolation(err) {
+			return 0, ErrVersionConflict
+		}
+		return 0, fmt.Errorf("append events: %w", err)
+	}
+	return version, nil
+}
+
+// ReadStream reads up to limit events of the stream after the given version,
+// in the order of versions.
+func (s *EventStore) ReadStream(ctx context.Context, stream string, afterVersion int64, limit uint64) ([]*Event, error) {
+	query := SQL.Select(eventColumns...).From("events").
+		Where(sq.Eq{"stream": stream}).
+		Where(sq.Gt{"version": afterVersion}).
+		OrderBy("version").
+		Limit(limit)
+	return s.read(ctx, query)
+}
+
+// ReadAll reads up to limit events of all the streams after the given global
+// position, in the global order.
+//
+// NOTE: the global positions are assigned on insertion, a transaction
+// committed later may have a smaller position. Readers tracking the position
+// should tolerate this, e.g. by re-reading a short window behind.
+func (s *EventStore) ReadAll(ctx context.Context, afterPosition int64, limit uint64) ([]*Event, error) {
+	query := SQL.Select(eventColumns...).From("events").
+		Where(sq.Gt{"id": afterPosition}).
+		OrderBy("id").
+		Limit(limit)
+	return s.read(ctx, query)
+}
+
+func (s *EventStore) read(ctx context.Context, query sq.SelectBuilder) ([]*Event, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return readEvents(ctx, q, query)
+}
+
+func readEvents(ctx context.Context, q Querier, query sq.SelectBuilder) ([]*Event, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	var events []*Event
+	if err := scanAPI(&events).Select(ctx, q, &events, sqlstr, args...); err != nil {
+		return nil, fmt.Errorf("read events: %w", err)
+	}
+	return events, nil
+}
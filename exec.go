@@ -2,13 +2,22 @@ package pg
 
 import (
 	"context"
+	"fmt"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
 )
 
+// maxBindParams is the max number of the bind parameters of a statement, a
+// limit of the PostgreSQL protocol.
+const maxBindParams = 65535
+
 // Exec simplifies running a INSERT/UPDATE/DELETE query.
 // Returns the number of rows affected on success and execution error on failure.
 //
+// A multi-row INSERT exceeding the limit of bind parameters (65535) is split
+// into multiple statements, which run in a transaction (see WithinTx).
+//
 // Example:
 //
 //	query := pg.SQL.Delete("users").Where(sq.Eq{"id": 1})
@@ -18,10 +27,152 @@ func Exec(ctx context.Context, query sq.Sqlizer) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if len(args) > maxBindParams {
+		insert, ok := query.(sq.InsertBuilder)
+		if !ok {
+			return 0, fmt.Errorf("too many bind parameters: %d > %d", len(args), maxBindParams)
+		}
+		return execInChunks(ctx, insert, len(args))
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
 
-	res, err := DB().Exec(ctx, sqlstr, args...)
+	res, err := q.Exec(ctx, sqlstr, args...)
 	if err != nil {
 		return 0, err
 	}
 	return res.RowsAffected(), nil
 }
+
+// InsertMany inserts the rows (the values of the columns) into the table in
+// one statement, or in multiple statements in a transaction if the rows
+// exceed the limit of bind parameters. Returns the number of the inserted
+// rows.
+//
+// Example:
+//
+//	n, err := pg.InsertMany(ctx, "events", []string{"type", "data"}, [][]any{
+//		{"signup", data1},
+//		{"login", data2},
+//	})
+func InsertMany(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	query := SQL.Insert(QuoteIdent(table)).Columns(columns...)
+	for _, row := range rows {
+		query = query.Values(row...)
+	}
+	return Exec(ctx, query)
+}
+
+// execInChunks splits the rows of the INSERT into chunks within the limit of
+// bind parameters, and runs them in a transaction.
+func execInChunks(ctx context.Context, insert sq.InsertBuilder, countArgs int) (int64, error) {
+	values, _ := builder.Get(insert, "Values")
+	rows, _ := values.([][]any)
+	if len(rows) == 0 {
+		return 0, fmt.Errorf("too many bind parameters: %d > %d", countArgs, maxBindParams)
+	}
+
+	// The bind parameters out of the rows, e.g. of an ON CONFLICT clause, are
+	// repeated in every chunk.
+	rowArgs := make([]int, len(rows))
+	fixedArgs := countArgs
+	for i, row := range rows {
+		for _, value := range row {
+			if expr, ok := value.(sq.Sqlizer); ok {
+				_, args, err := expr.ToSql()
+				if err != nil {
+					return 0, err
+				}
+				rowArgs[i] += len(args)
+			} else {
+				rowArgs[i]++
+			}
+		}
+		fixedArgs -= rowArgs[i]
+	}
+
+	var total int64
+	err := WithinTx(ctx, func(ctx context.Context) error {
+		for start := 0; start < len(rows); {
+			end, count := start, fixedArgs
+			for end < len(rows) && count+rowArgs[end] <= maxBindParams {
+				count += rowArgs[end]
+				end++
+			}
+			if end == start {
+				return fmt.Errorf("too many bind parameters in row #%d", start)
+			}
+
+			chunk := builder.Set(insert, "Values", rows[start:end]).(sq.InsertBuilder)
+			n, err := Exec(ctx, chunk)
+			if err != nil {
+				return fmt.Errorf("insert rows #%d-#%d: %w", start, end-1, err)
+			}
+			total += n
+			start = end
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// ExecReturning runs an INSERT/UPDATE/DELETE query with a RETURNING clause,
+// and scans the returned row into dest. Returns nil if no rows returned, like
+// Get.
+//
+// Example:
+//
+//	var user = new(User)
+//	query := pg.SQL.Update("users").Set("name", "John").Where(sq.Eq{"id": 1}).Suffix("RETURNING *")
+//	user, err = pg.ExecReturning(ctx, user, query)
+func ExecReturning[T any](ctx context.Context, dest *T, query sq.Sqlizer) (*T, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	err = scanAPI(dest).Get(ctx, q, dest, sqlstr, args...)
+	return ReturnsNilWhenNotFound(dest, err)
+}
+
+// ExecReturningAll works like ExecReturning, but returns all the returned
+// rows.
+//
+// Example:
+//
+//	query := pg.SQL.Delete("sessions").Where("expiry < now()").Suffix("RETURNING *")
+//	sessions, err := pg.ExecReturningAll[*Session](ctx, query)
+func ExecReturningAll[T any](ctx context.Context, query sq.Sqlizer) ([]T, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var rows []T
+	if err := scanAPI(&rows).Select(ctx, q, &rows, sqlstr, args...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,149 @@
+package pg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportsSchema is the DDL of the table storing the checkpoints of the
+// resumable exports. Run it along with the migrations of the application.
+const ExportsSchema = `
+CREATE TABLE IF NOT EXISTS exports (
+	id         text PRIMARY KEY,
+	cursor     text NOT NULL DEFAULT '',
+	exported   bigint NOT NULL DEFAULT 0,
+	done       boolean NOT NULL DEFAULT false,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+`
+
+// ErrExportNotFound is returned when resuming an export that doesn't exist.
+var ErrExportNotFound = errors.New("pg: export not found")
+
+const (
+	defaultExportBatchSize = 1000
+	defaultExportTimeout   = 20 * time.Second
+)
+
+// ResumableExport exports the rows of a query across multiple calls (e.g.
+// HTTP requests), each within a time budget, so that the clients behind
+// gateway timeouts can still pull millions of rows. The rows are read in
+// batches with the seek method (see SeekList) and the keyset checkpoint is
+// persisted in the exports table (see ExportsSchema) after each batch.
+//
+// Example:
+//
+//	var usersExport = &pg.ResumableExport[*User]{
+//		Query:      pg.SQL.Select("*").From("users"),
+//		KeyColumns: []string{"id"},
+//	}
+//
+//	id, err := usersExport.Start(ctx) // hand the id to the client
+//	done, err := usersExport.Next(ctx, id, func(users []*User) error {
+//		return csvWriter.Write(users)
+//	})
+type ResumableExport[T any] struct {
+	Query      sq.SelectBuilder
+	KeyColumns []string      // see SeekList
+	BatchSize  int64         // 1000 by default
+	Timeout    time.Duration // the time budget of a call, 20s by default
+}
+
+// Start creates a new export and returns its id.
+func (e *ResumableExport[T]) Start(ctx context.Context) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate export id: %w", err)
+	}
+	id := hex.EncodeToString(b)
+
+	if _, err := Exec(ctx, SQL.Insert("exports").Columns("id").Values(id)); err != nil {
+		return "", fmt.Errorf("create export: %w", err)
+	}
+	return id, nil
+}
+
+// Next exports the following batches of the export until the time budget (or
+// the deadline of the context) is used up, calling write with each batch. The
+// checkpoint advances only if write succeeds, thus a failed call is resumed
+// from the last written batch. Returns true if all the rows have been
+// exported.
+func (e *ResumableExport[T]) Next(ctx context.Context, id string, write func(batch []T) error) (bool, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	var cursor string
+	var done bool
+	err = q.QueryRow(ctx, "SELECT cursor, done FROM exports WHERE id = $1", id).Scan(&cursor, &done)
+	release()
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrExportNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("load export %q: %w", id, err)
+	}
+	if done {
+		return true, nil
+	}
+
+	deadline := time.Now().Add(e.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	batchSize := e.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultExportBatchSize
+	}
+	p := NewSeekPagination(batchSize)
+	for time.Now().Before(deadline) {
+		p.SetCursor(cursor)
+		var batch []T
+		hasNext, err := SeekList(ctx, &batch, e.Query, p, e.KeyColumns...)
+		if err != nil {
+			return false, fmt.Errorf("read export %q: %w", id, err)
+		}
+		if len(batch) > 0 {
+			if err := write(batch); err != nil {
+				return false, err
+			}
+		}
+
+		cursor, done = p.Cursor(), !hasNext
+		query := SQL.Update("exports").
+			Set("cursor", cursor).
+			Set("exported", sq.Expr("exported + ?", len(batch))).
+			Set("done", done).
+			Set("updated_at", sq.Expr("now()")).
+			Where(sq.Eq{"id": id})
+		if _, err := Exec(ctx, query); err != nil {
+			return false, fmt.Errorf("save checkpoint of export %q: %w", id, err)
+		}
+		if done {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PurgeExports deletes the exports not updated for the given duration.
+func PurgeExports(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := SQL.Delete("exports").Where("updated_at < now() - ? * interval '1 second'", olderThan.Seconds())
+	return Exec(ctx, query)
+}
+
+func (e *ResumableExport[T]) timeout() time.Duration {
+	if e.Timeout <= 0 {
+		return defaultExportTimeout
+	}
+	return e.Timeout
+}
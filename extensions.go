@@ -0,0 +1,59 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// EnsureExtensions creates the extensions missing in the database. The
+// failures are reported per extension with the way to fix them, i.e. the
+// extensions not available on the server need their packages installed,
+// e.g. pgvector for "vector", and the ones the current user isn't permitted
+// to create need a superuser (or the database owner for the trusted ones) to
+// create them. Run it before the migrations of the application.
+//
+// Example:
+//
+//	if err := pg.EnsureExtensions(ctx, "pg_trgm", "uuid-ossp", "vector"); err != nil {
+//		log.Fatal(err)
+//	}
+func EnsureExtensions(ctx context.Context, names ...string) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var errs []error
+	for _, name := range names {
+		var installed, available bool
+		err := q.QueryRow(ctx, `SELECT
+			EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1),
+			EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = $1)`, name,
+		).Scan(&installed, &available)
+		if err != nil {
+			return fmt.Errorf("check extension %s: %w", name, err)
+		}
+		if installed {
+			continue
+		}
+		if !available {
+			errs = append(errs, fmt.Errorf("extension %s is not available on the server, install its package first", name))
+			continue
+		}
+
+		ddl := "CREATE EXTENSION IF NOT EXISTS " + Ident(name)
+		if _, err := q.Exec(ctx, ddl); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "42501" {
+				errs = append(errs, fmt.Errorf("no permission to create extension %s, run %q as a superuser: %w", name, ddl, err))
+			} else {
+				errs = append(errs, fmt.Errorf("create extension %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,231 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FlagsSchema is the DDL of the table storing the feature flags, along with a
+// trigger notifying the changes on the "feature_flags" channel. Run it along
+// with the migrations of the application.
+const FlagsSchema = `
+CREATE TABLE IF NOT EXISTS feature_flags (
+	name       text PRIMARY KEY,
+	value      jsonb NOT NULL,
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE OR REPLACE FUNCTION notify_feature_flags() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		PERFORM pg_notify('feature_flags', OLD.name);
+	ELSE
+		PERFORM pg_notify('feature_flags', NEW.name);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS feature_flags_notify ON feature_flags;
+CREATE TRIGGER feature_flags_notify AFTER INSERT OR UPDATE OR DELETE ON feature_flags
+	FOR EACH ROW EXECUTE FUNCTION notify_feature_flags();
+`
+
+// flagsChannel is the channel the changes of the flags are notified on.
+const flagsChannel = "feature_flags"
+
+// flagsCacheTTL is how long the cached flags are used before reloading them,
+// when the store isn't watching the changes.
+const flagsCacheTTL = time.Minute
+
+// Flags is the feature flag store backed by the feature_flags table (see
+// FlagsSchema).
+//
+// Example:
+//
+//	go pg.Flags.Watch(ctx) // optional, refresh the cache on changes
+//	if pg.Flags.Get(ctx, "new_checkout", false) {
+//		...
+//	}
+var Flags = new(FlagStore)
+
+// FlagStore is an in-memory cache of the feature flags. The cache is reloaded
+// when it's older than a minute, or refreshed on every change (LISTEN/NOTIFY)
+// while Watch is running.
+type FlagStore struct {
+	mu       sync.RWMutex
+	values   map[string]json.RawMessage
+	loadedAt time.Time
+	watching atomic.Bool
+}
+
+// Get returns the value of a boolean flag. Returns defaultVal if the flag is
+// not found, not a boolean, or the flags can't be loaded.
+func (s *FlagStore) Get(ctx context.Context, name string, defaultVal bool) bool {
+	var v bool
+	if found, err := s.Decode(ctx, name, &v); !found || err != nil {
+		return defaultVal
+	}
+	return v
+}
+
+// Decode decodes the JSON value of a flag into dest. Returns false if the
+// flag is not found.
+func (s *FlagStore) Decode(ctx context.Context, name string, dest any) (bool, error) {
+	if s.stale() {
+		if err := s.Reload(ctx); err != nil && s.isEmpty() {
+			return false, err
+		}
+	}
+
+	s.mu.RLock()
+	raw, ok := s.values[name]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, dest)
+}
+
+// Set creates or updates a flag. The value is stored as JSON. If the context
+// carries a transaction (see WithinTx), the cache is updated after it commits.
+func (s *FlagStore) Set(ctx context.Context, name string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal flag value: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = q.Exec(ctx, `INSERT INTO feature_flags (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = now()`, name, raw)
+	if err != nil {
+		return fmt.Errorf("set flag %q: %w", name, err)
+	}
+	AfterCommit(ctx, func(context.Context) { s.store(name, raw) })
+	return nil
+}
+
+// Delete deletes a flag.
+func (s *FlagStore) Delete(ctx context.Context, name string) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := q.Exec(ctx, "DELETE FROM feature_flags WHERE name = $1", name); err != nil {
+		return fmt.Errorf("delete flag %q: %w", name, err)
+	}
+	AfterCommit(ctx, func(context.Context) { s.store(name, nil) })
+	return nil
+}
+
+// Reload loads all the flags from the database into the cache.
+func (s *FlagStore) Reload(ctx context.Context) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, "SELECT name, value FROM feature_flags")
+	if err != nil {
+		return fmt.Errorf("load flags: %w", err)
+	}
+	values := make(map[string]json.RawMessage)
+	var name string
+	var raw json.RawMessage
+	_, err = pgx.ForEachRow(rows, []any{&name, &raw}, func() error {
+		values[name] = raw
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("load flags: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = values
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// Watch listens to the changes of the flags and refreshes the cache
+// accordingly, until the context is done or the connection fails. It holds a
+// dedicated connection from the pool.
+func (s *FlagStore) Watch(ctx context.Context) error {
+	conn, err := acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+flagsChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	defer conn.Exec(context.Background(), "UNLISTEN "+flagsChannel)
+
+	// Reload after listening, so that no changes are missed.
+	if err := s.Reload(ctx); err != nil {
+		return err
+	}
+	s.watching.Store(true)
+	defer s.watching.Store(false)
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		name := notification.Payload
+		var raw json.RawMessage
+		err = conn.QueryRow(ctx, "SELECT value FROM feature_flags WHERE name = $1", name).Scan(&raw)
+		if errors.Is(err, pgx.ErrNoRows) {
+			raw, err = nil, nil
+		}
+		if err != nil {
+			return fmt.Errorf("refresh flag %q: %w", name, err)
+		}
+		s.store(name, raw)
+	}
+}
+
+// store updates a flag in the cache, nil value means deleted.
+func (s *FlagStore) store(name string, raw json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		return // not loaded yet
+	}
+	if raw == nil {
+		delete(s.values, name)
+	} else {
+		s.values[name] = raw
+	}
+}
+
+func (s *FlagStore) stale() bool {
+	if s.watching.Load() {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values == nil || time.Since(s.loadedAt) > flagsCacheTTL
+}
+
+func (s *FlagStore) isEmpty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values == nil
+}
@@ -0,0 +1,68 @@
+package pg
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// WithFullTextSearch returns a ListOption that filters the rows whose tsvector
+// column matches the query of the user, i.e. column @@ plainto_tsquery($1).
+// The text search configuration (e.g. "english") is optional, the default one
+// of the database (default_text_search_config) applies otherwise.
+//
+// When the given query is empty, the returned ListOption is a no-op.
+//
+// Example:
+//
+//	pagination, err := pg.List(ctx, articles, pg.SQL.Select("*").From("articles"),
+//		pg.WithFullTextSearch("search_vector", q, "english"),
+//		pg.SortByRank("search_vector", q, "english"),
+//	)
+func WithFullTextSearch(tsvectorColumn, query string, config ...string) ListOption {
+	return withTextSearch("plainto_tsquery", tsvectorColumn, query, config)
+}
+
+// WithWebSearch works like WithFullTextSearch, but parses the query with
+// websearch_to_tsquery, which supports the syntax of the web search engines,
+// e.g. "quoted phrases", OR and -excluded words.
+func WithWebSearch(tsvectorColumn, query string, config ...string) ListOption {
+	return withTextSearch("websearch_to_tsquery", tsvectorColumn, query, config)
+}
+
+// SortByRank returns a ListOption that sorts the result by the relevance
+// (ts_rank) of the tsvector column to the query, the most relevant first. See
+// WithFullTextSearch.
+//
+// When the given query is empty, the returned ListOption is a no-op.
+func SortByRank(tsvectorColumn, query string, config ...string) ListOption {
+	if query == "" {
+		return &withSortByOption{}
+	}
+	tsquery, args := tsQuery("plainto_tsquery", query, config)
+	return &withSortByOption{
+		columnName: fmt.Sprintf("ts_rank(%s, %s)", tsvectorColumn, tsquery),
+		direction:  "desc",
+		args:       args,
+	}
+}
+
+func withTextSearch(parser, tsvectorColumn, query string, config []string) ListOption {
+	return filterOn(tsvectorColumn, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if query == "" {
+			return sb
+		}
+		tsquery, args := tsQuery(parser, query, config)
+		return sb.Where(tsvectorColumn+" @@ "+tsquery, args...)
+	})
+}
+
+// tsQuery returns the expression parsing the query into a tsquery, and its
+// args.
+func tsQuery(parser, query string, config []string) (string, []any) {
+	if len(config) > 0 && config[0] != "" {
+		return parser + "(?::regconfig, ?)", []any{config[0], query}
+	}
+	return parser + "(?)", []any{query}
+}
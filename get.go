@@ -2,9 +2,11 @@ package pg
 
 import (
 	"context"
+	"fmt"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/lann/builder"
 )
 
 // Get simplifies running a SELECT query which aims to find only one row of record.
@@ -23,6 +25,153 @@ func Get[T any](ctx context.Context, v *T, query sq.SelectBuilder) (*T, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = pgxscan.Get(ctx, DB(), v, sqlstr, args...)
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	err = scanAPI(v).Get(ctx, q, v, sqlstr, args...)
 	return ReturnsNilWhenNotFound(v, err)
 }
+
+// GetManyOrdered fetches the rows of the table by their ids (the "id" column)
+// and returns them in the order of ids, e.g. the ranking of a search engine.
+// The ids not found are skipped, and the duplicated ones appear as many times.
+// See JoinValues for the array types of the ids.
+//
+// Example:
+//
+//	ids := searchResult.IDs() // []int64, ranked
+//	products, err := pg.GetManyOrdered[*Product](ctx, "products", ids)
+func GetManyOrdered[T any, K any](ctx context.Context, table string, ids []K) ([]T, error) {
+	query := SQL.Select("t.*").From(QuoteIdent(table) + " t")
+	query = JoinValues("t.id", ids).Apply(query)
+	query = SortByValues("t.id").Apply(query)
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var vs []T
+	if err := scanAPI(&vs).Select(ctx, q, &vs, sqlstr, args...); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Exists tells whether the query returns any row, i.e. SELECT EXISTS (query).
+//
+// Example:
+//
+//	taken, err := pg.Exists(ctx, pg.SQL.Select("1").From("users").Where(sq.Eq{"email": email}))
+func Exists(ctx context.Context, query sq.SelectBuilder) (bool, error) {
+	sqlstr, args, err := SQL.Select().Column(sq.Expr("EXISTS (?)", query.PlaceholderFormat(sq.Question))).ToSql()
+	if err != nil {
+		return false, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	var exists bool
+	if err := q.QueryRow(ctx, sqlstr, args...).Scan(&exists); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// Count returns the number of the rows the query returns. The columns and the
+// ORDER BY of the query are replaced by COUNT(*), while the grouped, the
+// distinct and the limited queries are counted as subqueries.
+//
+// Example:
+//
+//	n, err := pg.Count(ctx, pg.SQL.Select("*").From("orders").Where(sq.Eq{"status": "pending"}))
+func Count(ctx context.Context, query sq.SelectBuilder) (int64, error) {
+	var countQuery sq.SelectBuilder
+	_, limited := builder.Get(query, "Limit")
+	_, offset := builder.Get(query, "Offset")
+	if limited || offset {
+		countQuery = SQL.Select("COUNT(*)").FromSelect(query, "t")
+	} else {
+		countQuery = toCountQuery(builder.Delete(query, "OrderByParts").(sq.SelectBuilder))
+	}
+	sqlstr, args, err := countQuery.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble count query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var count int64
+	if err := q.QueryRow(ctx, sqlstr, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count records: %w", err)
+	}
+	return count, nil
+}
+
+// GetScalar runs a query of a single value, e.g. an aggregate (SUM, MAX) or an
+// id, and scans it into T without a struct. Returns pgx.ErrNoRows if no rows
+// found. A NULL value, e.g. the SUM of no rows, needs a nullable T, e.g.
+// *int64 or sql.NullInt64.
+//
+// Example:
+//
+//	total, err := pg.GetScalar[*int64](ctx, pg.SQL.Select("SUM(amount)").From("payments").Where(sq.Eq{"user_id": userID}))
+//	id, err := pg.GetScalar[int64](ctx, pg.SQL.Select("id").From("users").Where(sq.Eq{"email": email}))
+func GetScalar[T any](ctx context.Context, query sq.SelectBuilder) (T, error) {
+	var v T
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return v, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return v, err
+	}
+	defer release()
+
+	err = q.QueryRow(ctx, sqlstr, args...).Scan(&v)
+	return v, err
+}
+
+// SelectScalars runs a query of a single column and scans the values into a
+// slice of T without a struct.
+//
+// Example:
+//
+//	ids, err := pg.SelectScalars[int64](ctx, pg.SQL.Select("id").From("users").Where(sq.Eq{"status": "inactive"}))
+func SelectScalars[T any](ctx context.Context, query sq.SelectBuilder) ([]T, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[T])
+}
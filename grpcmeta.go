@@ -0,0 +1,49 @@
+package pg
+
+import (
+	"context"
+	"errors"
+)
+
+// GRPCSetHeader sends the metadata as the gRPC response header of the call of
+// the context. The package doesn't depend on gRPC, so it must be set to
+// grpc.SetHeader before calling SetGRPCPaginationMetadata.
+//
+// Example:
+//
+//	pg.GRPCSetHeader = func(ctx context.Context, md map[string][]string) error {
+//		return grpc.SetHeader(ctx, md)
+//	}
+var GRPCSetHeader func(ctx context.Context, md map[string][]string) error
+
+// GRPCPagination is the pagination written to the gRPC response metadata,
+// i.e. *OffsetPagination or *SeekPagination.
+type GRPCPagination interface {
+	XPaginationHeader() string
+}
+
+// SetGRPCPaginationMetadata writes paging info to the gRPC response metadata,
+// as SetResponseHeaders does to the HTTP response, i.e. the x-pagination key.
+// There's no Link header, since there's no URL in gRPC.
+//
+// Example:
+//
+//	func (s *server) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+//		result, err := pg.ListItems[*User](ctx, pg.SQL.Select("*").From("users"),
+//			pg.WithOffsetPagination(&pg.OffsetPagination{Page: req.Page, PerPage: req.PerPage}))
+//		if err != nil {
+//			return nil, err
+//		}
+//		if err := pg.SetGRPCPaginationMetadata(ctx, result.Pagination); err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+func SetGRPCPaginationMetadata(ctx context.Context, pagination GRPCPagination) error {
+	if GRPCSetHeader == nil {
+		return errors.New("pg.GRPCSetHeader not set")
+	}
+	return GRPCSetHeader(ctx, map[string][]string{
+		"x-pagination": {pagination.XPaginationHeader()},
+	})
+}
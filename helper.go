@@ -1,7 +1,12 @@
 package pg
 
 import (
+	"errors"
+	"net"
+	"strings"
+
 	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // ReturnsNilWhenNotFound swallows the `pgxscan.NotFound` error and returns nil.
@@ -16,3 +21,43 @@ func ReturnsNilWhenNotFound[T any](v *T, err error) (*T, error) {
 
 	return v, err
 }
+
+// IsUniqueViolation returns true if the error is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsExclusionViolation returns true if the error is an exclusion constraint
+// violation, e.g. an overlapping booking. See TryReserve.
+func IsExclusionViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23P01"
+}
+
+// IsUnavailable returns true if the error means the database is unreachable
+// rather than the query failed, e.g. a refused connection, a server shutting
+// down, or the pool exhausted (see SetMaxAcquireWait).
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrPoolExhausted) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is the connection exceptions, 57P01-57P03 the shutdowns.
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	return false
+}
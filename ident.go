@@ -0,0 +1,134 @@
+package pg
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ident returns the quoted (possibly schema-qualified) identifier of the
+// parts, e.g. Ident("tenant_42", "Orders") is "tenant_42"."Orders".
+//
+// Example:
+//
+//	query := pg.SQL.Select("*").From(pg.Ident(tenantSchema, "orders"))
+func Ident(parts ...string) string {
+	return pgx.Identifier(parts).Sanitize()
+}
+
+// QuoteIdent quotes the parts of a (possibly schema-qualified) name which need
+// quoting, i.e. the ones with uppercase letters or special characters, and the
+// reserved words, e.g. public.User is public."User". The quoted parts are kept
+// as they are, thus it's safe to quote a name more than once. The names with
+// spaces or parentheses are considered as expressions (e.g. "users AS u") and
+// returned unchanged.
+//
+// The helpers taking table names (SyncSet, UpsertSCD2, CloseAndInsert, etc.)
+// quote them with QuoteIdent.
+func QuoteIdent(name string) string {
+	if name == "" || strings.ContainsAny(name, " \t\n()") {
+		return name
+	}
+
+	parts, quoted, ok := splitIdent(name)
+	if !ok {
+		return name
+	}
+	for i, part := range parts {
+		if quoted[i] {
+			parts[i] = pgx.Identifier{part}.Sanitize()
+		} else {
+			parts[i] = quotePart(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitIdent splits a (possibly schema-qualified) name into its parts, and
+// unquotes the quoted ones, e.g. public."My ""Table""" is public and
+// My "Table". Returns false if the name is malformed, e.g. has unbalanced
+// quotes.
+func splitIdent(name string) (parts []string, quoted []bool, ok bool) {
+	for rest := name; rest != ""; {
+		var part string
+		isQuoted := strings.HasPrefix(rest, `"`)
+		if isQuoted {
+			// A quoted part ends with a quote not followed by another one.
+			end := 1
+			for end < len(rest) {
+				if rest[end] == '"' {
+					if end+1 < len(rest) && rest[end+1] == '"' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, nil, false // unbalanced quotes
+			}
+			part, rest = strings.ReplaceAll(rest[1:end], `""`, `"`), rest[end+1:]
+		} else if i := strings.IndexByte(rest, '.'); i >= 0 {
+			part, rest = rest[:i], rest[i:]
+		} else {
+			part, rest = rest, ""
+		}
+		parts = append(parts, part)
+		quoted = append(quoted, isQuoted)
+
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, nil, false // trailing dot
+			}
+		} else if rest != "" {
+			return nil, nil, false // garbage after a quoted part
+		}
+	}
+	return parts, quoted, len(parts) > 0
+}
+
+func quotePart(part string) string {
+	if part == "*" || !needsQuoting(part) {
+		return part
+	}
+	return pgx.Identifier{part}.Sanitize()
+}
+
+func needsQuoting(part string) bool {
+	if part == "" || part[0] >= '0' && part[0] <= '9' {
+		return true
+	}
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return true
+		}
+	}
+	return reservedWords[part]
+}
+
+// reservedWords are the reserved key words of PostgreSQL, which can't be used
+// as table or column names unquoted.
+// See: https://www.postgresql.org/docs/current/sql-keywords-appendix.html
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true, "both": true,
+	"case": true, "cast": true, "check": true, "collate": true, "column": true,
+	"constraint": true, "create": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true, "else": true,
+	"end": true, "except": true, "false": true, "fetch": true, "for": true,
+	"foreign": true, "from": true, "grant": true, "group": true, "having": true,
+	"in": true, "initially": true, "intersect": true, "into": true,
+	"lateral": true, "leading": true, "limit": true, "localtime": true,
+	"localtimestamp": true, "not": true, "null": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "placing": true, "primary": true,
+	"references": true, "returning": true, "select": true, "session_user": true,
+	"some": true, "symmetric": true, "system_user": true, "table": true,
+	"then": true, "to": true, "trailing": true, "true": true, "union": true,
+	"unique": true, "user": true, "using": true, "variadic": true, "when": true,
+	"where": true, "window": true, "with": true,
+}
@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"users", "users"},
+		{"public.users", "public.users"},
+		{"users.*", "users.*"},
+		{"User", `"User"`},
+		{"public.User", `public."User"`},
+		{"user", `"user"`},
+		{"order_items2", "order_items2"},
+		{"2fa", `"2fa"`},
+		{"my-table", `"my-table"`},
+		{`"User"`, `"User"`},
+		{`public."User"`, `public."User"`},
+		{`"my.schema"."My Table"`, `"my.schema"."My Table"`},
+		{`say"hi`, `"say""hi"`},
+		{`"say""hi"`, `"say""hi"`},
+		{`s."a""b".c`, `s."a""b".c`},
+		{Ident("tenant_42", "Orders"), `"tenant_42"."Orders"`},
+		// unchanged: expressions and malformed names
+		{"", ""},
+		{"users AS u", "users AS u"},
+		{"lower(name)", "lower(name)"},
+		{`"unbalanced`, `"unbalanced`},
+		{"trailing.", "trailing."},
+		{`"a"b`, `"a"b`},
+	} {
+		got := QuoteIdent(tc.name)
+		if got != tc.want {
+			t.Errorf("QuoteIdent(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+		if again := QuoteIdent(got); again != got {
+			t.Errorf("QuoteIdent(QuoteIdent(%q)) = %q, want %q", tc.name, again, got)
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	for _, tc := range []struct {
+		parts []string
+		want  string
+	}{
+		{[]string{"users"}, `"users"`},
+		{[]string{"tenant_42", "Orders"}, `"tenant_42"."Orders"`},
+		{[]string{`say"hi`}, `"say""hi"`},
+		{[]string{"a.b"}, `"a.b"`},
+	} {
+		if got := Ident(tc.parts...); got != tc.want {
+			t.Errorf("Ident(%q) = %q, want %q", tc.parts, got, tc.want)
+		}
+	}
+}
+
+func TestSplitIdent(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want []string // nil if malformed
+	}{
+		{"events", []string{"events"}},
+		{"audit.events", []string{"audit", "events"}},
+		{"MyTable", []string{"MyTable"}},
+		{`"MyTable"`, []string{"MyTable"}},
+		{Ident("s", "t"), []string{"s", "t"}},
+		{`"my.schema"."My ""Table"""`, []string{"my.schema", `My "Table"`}},
+		{QuoteIdent("public.User"), []string{"public", "User"}},
+		{`"unbalanced`, nil},
+		{"trailing.", nil},
+		{`"a"b`, nil},
+		{"", nil},
+	} {
+		got, _, ok := splitIdent(tc.name)
+		if !ok {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitIdent(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
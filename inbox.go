@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// InboxSchema is the DDL of the table recording the processed messages. Run
+// it along with the migrations of the application.
+const InboxSchema = `
+CREATE TABLE IF NOT EXISTS inbox (
+	message_id   text PRIMARY KEY,
+	processed_at timestamptz NOT NULL DEFAULT now()
+);
+`
+
+// Inbox de-duplicates the messages consumed from external feeds (Kafka, SQS,
+// etc.), backed by the inbox table (see InboxSchema).
+//
+// Example:
+//
+//	processed, err := pg.Inbox.Process(ctx, msg.ID, func(ctx context.Context, tx pgx.Tx) error {
+//		... // apply the effects of the message with tx
+//	})
+var Inbox = new(InboxStore)
+
+// InboxStore records the processed message IDs. See Inbox.
+type InboxStore struct{}
+
+// Process runs fn in a transaction, along with recording the message ID as
+// processed. If the message has been processed, fn is not called and false is
+// returned. Since the effects of fn and the record are committed (or rolled
+// back) together, the effects of a message are applied exactly once, even if
+// the message is delivered more than once. A concurrent delivery of the same
+// message waits until the first one is done.
+//
+// The transaction is carried by the context passed to fn (see WithinTx). If
+// the context carries a transaction already, Process runs in it.
+func (s *InboxStore) Process(ctx context.Context, messageID string, fn func(ctx context.Context, tx pgx.Tx) error) (processed bool, err error) {
+	err = RunInTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		tag, err := withMiddlewares(tx).Exec(ctx, "INSERT INTO inbox (message_id) VALUES ($1) ON CONFLICT DO NOTHING", messageID)
+		if err != nil {
+			return fmt.Errorf("record message %q: %w", messageID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return nil // processed
+		}
+
+		processed = true
+		return fn(ctx, tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return processed, nil
+}
+
+// IsProcessed returns true if the message has been processed.
+func (s *InboxStore) IsProcessed(ctx context.Context, messageID string) (bool, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	var processed bool
+	err = q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM inbox WHERE message_id = $1)", messageID).Scan(&processed)
+	return processed, err
+}
+
+// Purge deletes the records of the messages processed before the given time.
+// The messages redelivered after that are processed again, so keep the
+// records longer than the retention of the feeds.
+func (s *InboxStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return Exec(ctx, SQL.Delete("inbox").Where("processed_at < ?", before))
+}
@@ -0,0 +1,140 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IndexSuggestion is an index suggested by IndexAdvisor for a sequential scan
+// with a selective filter on a large table.
+type IndexSuggestion struct {
+	SQL          string   // the query
+	Table        string   // the scanned table
+	Filter       string   // the filter of the scan, as printed by EXPLAIN
+	Columns      []string // the columns of the filter
+	TableRows    float64  // the estimated rows of the table
+	FilteredRows float64  // the estimated rows after the filter
+	DDL          string   // e.g. CREATE INDEX ON users (email)
+}
+
+// filterColumnRe matches the columns compared in the filters printed by
+// EXPLAIN, e.g. "status" of "((status)::text = 'active'::text)".
+var filterColumnRe = regexp.MustCompile(`\(+(?:\w+\.)?"?(\w+)"?\)?(?:::[\w ]+\)?)?\s+(?:=|<>|<|>|<=|>=|~~\*?|IS)\s`)
+
+// IndexAdvisor returns a QueryMiddleware for the development mode, which
+// inspects the plans (EXPLAIN) of the SELECT queries, and reports an index
+// suggestion for each sequential scan on a table of at least minRows rows
+// with a filter keeping less than 10% of them, e.g. a ListOption filtering on
+// a column without an index. Each distinct query is inspected once, before it
+// runs, doubling its planning. Don't use it in production.
+//
+// Example:
+//
+//	if env == "development" {
+//		pg.Use(pg.IndexAdvisor(10000, func(s pg.IndexSuggestion) {
+//			log.Printf("seq scan on %s filtering %s, consider: %s", s.Table, s.Filter, s.DDL)
+//		}))
+//	}
+func IndexAdvisor(minRows float64, report func(IndexSuggestion)) QueryMiddleware {
+	var seen sync.Map
+	inspect := func(ctx context.Context, next Querier, sql string, args []any) {
+		if _, operation := parseTableOperation(sql); operation != "SELECT" {
+			return
+		}
+		if _, loaded := seen.LoadOrStore(sql, true); loaded {
+			return
+		}
+		for _, s := range adviseIndexes(ctx, next, sql, args, minRows) {
+			report(s)
+		}
+	}
+
+	return func(next Querier) Querier {
+		return &QuerierFuncs{
+			Next: next,
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				inspect(ctx, next, sql, args)
+				return next.Query(ctx, sql, args...)
+			},
+			QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+				inspect(ctx, next, sql, args)
+				return next.QueryRow(ctx, sql, args...)
+			},
+		}
+	}
+}
+
+type planNode struct {
+	NodeType string     `json:"Node Type"`
+	Relation string     `json:"Relation Name"`
+	Filter   string     `json:"Filter"`
+	Rows     float64    `json:"Plan Rows"`
+	Plans    []planNode `json:"Plans"`
+}
+
+// adviseIndexes explains the query and suggests the indexes of its selective
+// sequential scans. The errors are ignored, the advice is best effort.
+func adviseIndexes(ctx context.Context, q Querier, sql string, args []any, minRows float64) []IndexSuggestion {
+	var plan []byte
+	if err := q.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sql, args...).Scan(&plan); err != nil {
+		return nil
+	}
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &plans); err != nil || len(plans) == 0 {
+		return nil
+	}
+
+	var suggestions []IndexSuggestion
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		for _, child := range node.Plans {
+			walk(child)
+		}
+		if node.NodeType != "Seq Scan" || node.Filter == "" {
+			return
+		}
+		var tableRows float64
+		if err := q.QueryRow(ctx, "SELECT reltuples::float8 FROM pg_class WHERE oid = to_regclass($1)", node.Relation).Scan(&tableRows); err != nil {
+			return
+		}
+		if tableRows < minRows || node.Rows > tableRows*0.1 {
+			return
+		}
+		columns := filterColumns(node.Filter)
+		if len(columns) == 0 {
+			return
+		}
+		suggestions = append(suggestions, IndexSuggestion{
+			SQL:          sql,
+			Table:        node.Relation,
+			Filter:       node.Filter,
+			Columns:      columns,
+			TableRows:    tableRows,
+			FilteredRows: node.Rows,
+			DDL:          fmt.Sprintf("CREATE INDEX ON %s (%s)", QuoteIdent(node.Relation), strings.Join(quoteIdents(columns), ", ")),
+		})
+	}
+	walk(plans[0].Plan)
+	return suggestions
+}
+
+// filterColumns returns the distinct columns compared in the filter.
+func filterColumns(filter string) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for _, m := range filterColumnRe.FindAllStringSubmatch(filter, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			columns = append(columns, m[1])
+		}
+	}
+	return columns
+}
@@ -0,0 +1,125 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Insert inserts the struct as a row of the table, and scans the inserted row
+// (RETURNING *) back into it, e.g. to fill in the generated id and the
+// defaults. The columns are mapped from the fields as the scanning does: the
+// "db" tags, or the snake case of the field names. The zero-valued fields
+// tagged with "omitempty" are omitted, so that the defaults of the columns
+// apply.
+//
+// Example:
+//
+//	type User struct {
+//		ID        int64     `db:"id,omitempty"`
+//		Email     string
+//		CreatedAt time.Time `db:"created_at,omitempty"`
+//	}
+//
+//	user := &User{Email: "john@example.com"}
+//	err := pg.Insert(ctx, "users", user)
+func Insert[T any](ctx context.Context, table string, v *T) error {
+	columns, values, err := structColumns(v, false)
+	if err != nil {
+		return err
+	}
+	sqlstr, args, err := SQL.Insert(QuoteIdent(table)).
+		Columns(quoteIdents(columns)...).
+		Values(values...).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("assemble insert query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := scanAPI(v).Get(ctx, q, v, sqlstr, args...); err != nil {
+		return fmt.Errorf("insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+// structColumns returns the columns and the values of the fields of the
+// struct (or the struct v points to). The fields of the embedded structs are
+// flattened. The zero-valued fields are omitted if omitZero, otherwise only
+// the ones tagged with "omitempty".
+func structColumns(v any, omitZero bool) (columns []string, values []any, err error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("expect a struct, got %T", v)
+	}
+
+	mapper := namingOf(rv.Type())
+	seen := make(map[string]bool)
+	var walk func(rv reflect.Value)
+	walk = func(rv reflect.Value) {
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue // unexported
+			}
+			tag, hasTag := field.Tag.Lookup("db")
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				continue
+			}
+
+			fv := rv.Field(i)
+			if field.Anonymous && !hasTag {
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				if fv.Kind() == reflect.Struct {
+					walk(fv)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue // unexported embedded non-struct
+			}
+
+			if !hasTag || name == "" {
+				name = mapper(field.Name)
+			}
+			if seen[name] || (omitZero || opts == "omitempty") && fv.IsZero() {
+				continue
+			}
+			seen[name] = true
+			columns = append(columns, name)
+			values = append(values, fv.Interface())
+		}
+	}
+	walk(rv)
+
+	if len(columns) == 0 {
+		return nil, nil, errors.New("no columns to write")
+	}
+	return columns, values, nil
+}
+
+func quoteIdents(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdent(name)
+	}
+	return quoted
+}
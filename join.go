@@ -0,0 +1,56 @@
+package pg
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// WithJoin returns a ListOption that joins the table (JOIN ...) and applies
+// the filters on it, so that a reusable filter requiring another table
+// bundles its join, e.g. the users of an organization:
+//
+//	func InOrganization(orgID int64) pg.ListOption {
+//		return pg.WithJoin("memberships m ON m.user_id = users.id", pg.With("m.org_id", orgID))
+//	}
+//
+// The same join added by multiple options is added once.
+func WithJoin(join string, filters ...ListOption) ListOption {
+	return withJoin("JOIN", join, filters)
+}
+
+// WithInnerJoin works like WithJoin, with INNER JOIN.
+func WithInnerJoin(join string, filters ...ListOption) ListOption {
+	return withJoin("INNER JOIN", join, filters)
+}
+
+// WithLeftJoin works like WithJoin, with LEFT JOIN, e.g. to filter on the
+// absence of the joined rows with WithNull.
+func WithLeftJoin(join string, filters ...ListOption) ListOption {
+	return withJoin("LEFT JOIN", join, filters)
+}
+
+func withJoin(joinType, join string, filters []ListOption) ListOption {
+	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if !hasJoin(sb, joinType+" "+join) {
+			sb = sb.JoinClause(joinType + " " + join)
+		}
+		for _, filter := range filters {
+			sb = filter.Apply(sb)
+		}
+		return sb
+	})
+}
+
+// hasJoin tells whether the query has the join clause.
+func hasJoin(sb sq.SelectBuilder, clause string) bool {
+	joins, ok := builder.Get(sb, "Joins")
+	if !ok {
+		return false
+	}
+	for _, join := range joins.([]sq.Sqlizer) {
+		if sqlstr, _, err := join.ToSql(); err == nil && sqlstr == clause {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// JoinValues returns a ListOption that filters the rows whose column is one
+// of the values by joining against the array of the values unnested, i.e.
+// JOIN unnest($1::bigint[]) WITH ORDINALITY AS v_id(id, ordinality) ON t.id =
+// v_id.id, an alternative to the giant IN lists, bound as a single parameter.
+// The array type is derived from the type of the values, e.g. bigint[] for
+// int64, text[] for string, timestamptz[] for time.Time, defaulting to
+// text[]. Use SortByValues to keep the order of the values.
+//
+// Example:
+//
+//	pagination, err := pg.List(ctx, users, pg.SQL.Select("u.*").From("users u"),
+//		pg.JoinValues("u.id", ids), pg.SortByValues("u.id"))
+func JoinValues[T any](column string, values []T) ListOption {
+	name, alias := joinValuesAlias(column)
+	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Join(fmt.Sprintf("unnest(?::%s[]) WITH ORDINALITY AS %s(%s, ordinality) ON %s = %s.%s",
+			pgArrayElemType(reflect.TypeOf(values).Elem()), alias, name, column, alias, name), values)
+	})
+}
+
+// SortByValues returns a ListOption that sorts the result in the order of the
+// values joined by JoinValues on the column.
+func SortByValues(column string) ListOption {
+	_, alias := joinValuesAlias(column)
+	return WithSortBy(alias+".ordinality", "asc")
+}
+
+// joinValuesAlias returns the name of the column without the qualifier, and
+// the alias of the unnested values joined on it.
+func joinValuesAlias(column string) (name, alias string) {
+	name = column[strings.LastIndex(column, ".")+1:]
+	return name, "v_" + strings.Trim(name, `"`)
+}
+
+// pgArrayElemType returns the type of the elements of the array in
+// PostgreSQL of a slice of t.
+func pgArrayElemType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Time{}) {
+		return "timestamptz"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return "bigint"
+	case reflect.Int32, reflect.Uint16:
+		return "integer"
+	case reflect.Int16, reflect.Int8, reflect.Uint8:
+		return "smallint"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.Float32:
+		return "real"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Array:
+		if t.Len() == 16 && t.Elem().Kind() == reflect.Uint8 {
+			return "uuid" // e.g. uuid.UUID
+		}
+	}
+	return "text"
+}
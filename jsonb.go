@@ -0,0 +1,192 @@
+package pg
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// JSONB wraps a value stored in a jsonb column. It scans the column by
+// decoding the JSON into V, and encodes V as JSON when used as a query
+// argument. A NULL column scans into the zero value of T. It's also
+// (un)marshaled as V, so it's transparent in API payloads.
+//
+// Example:
+//
+//	type User struct {
+//		ID       int64
+//		Settings pg.JSONB[Settings]
+//	}
+//
+//	user, err := pg.Get(ctx, new(User), query)
+//	theme := user.Settings.V.Theme
+type JSONB[T any] struct {
+	V T
+}
+
+// Scan implements sql.Scanner.
+func (j *JSONB[T]) Scan(src any) error {
+	var raw []byte
+	switch src := src.(type) {
+	case nil:
+		var zero T
+		j.V = zero
+		return nil
+	case string:
+		raw = []byte(src)
+	case []byte:
+		raw = src
+	default:
+		return fmt.Errorf("cannot scan %T into JSONB", src)
+	}
+	return json.Unmarshal(raw, &j.V)
+}
+
+// Value implements driver.Valuer.
+func (j JSONB[T]) Value() (driver.Value, error) {
+	raw, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j JSONB[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.V)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *JSONB[T]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &j.V)
+}
+
+// MergeJSONB returns the expression merging partial (encoded as JSON) into the
+// jsonb column, i.e. the top-level keys of partial are added or replaced and
+// the other keys are kept. A NULL column is treated as an empty object. Use it
+// as the value of a SET clause.
+//
+// Example:
+//
+//	query := pg.SQL.Update("users").
+//		Set("settings", pg.MergeJSONB("settings", map[string]any{"theme": "dark"})).
+//		Where(sq.Eq{"id": 1})
+//	_, err := pg.Exec(ctx, query)
+func MergeJSONB(column string, partial any) sq.Sqlizer {
+	return jsonbMerge{column: column, partial: partial}
+}
+
+type jsonbMerge struct {
+	column  string
+	partial any
+}
+
+func (m jsonbMerge) ToSql() (string, []any, error) {
+	raw, err := json.Marshal(m.partial)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal partial of %s: %w", m.column, err)
+	}
+	return fmt.Sprintf("COALESCE(%s, '{}'::jsonb) || ?::jsonb", m.column), []any{string(raw)}, nil
+}
+
+// UpdateJSONPath returns the UPDATE query setting the value (encoded as JSON)
+// at the path of the jsonb column with jsonb_set, i.e. in place, without the
+// read-modify-write race of updating the whole document. The missing keys are
+// created. See SetJSONPath for more control.
+//
+// Example:
+//
+//	query := pg.UpdateJSONPath("users", "settings", []string{"notifications", "email"}, false).
+//		Where(sq.Eq{"id": 1})
+//	_, err := pg.Exec(ctx, query)
+func UpdateJSONPath(table, column string, path []string, value any) sq.UpdateBuilder {
+	return SQL.Update(table).Set(column, SetJSONPath(column, path, value, true))
+}
+
+// SetJSONPath returns the jsonb_set expression setting the value (encoded as
+// JSON) at the path of the jsonb column. If createMissing is false, nothing
+// is set when the path doesn't exist. A NULL column is treated as an empty
+// object. Use it as the value of a SET clause.
+func SetJSONPath(column string, path []string, value any, createMissing bool) sq.Sqlizer {
+	return jsonbSet{column: column, path: path, value: value, createMissing: createMissing}
+}
+
+type jsonbSet struct {
+	column        string
+	path          []string
+	value         any
+	createMissing bool
+}
+
+func (s jsonbSet) ToSql() (string, []any, error) {
+	if len(s.path) == 0 {
+		return "", nil, fmt.Errorf("empty json path of %s", s.column)
+	}
+	raw, err := json.Marshal(s.value)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal value of %s: %w", s.column, err)
+	}
+	sqlstr := fmt.Sprintf("jsonb_set(COALESCE(%s, '{}'::jsonb), ?::text[], ?::jsonb, %t)", s.column, s.createMissing)
+	return sqlstr, []any{s.path, string(raw)}, nil
+}
+
+// WithJSONBContains returns a ListOption that filters the rows whose jsonb
+// column contains the value (encoded as JSON), i.e. column @> value, e.g.
+// WithJSONBContains("attributes", map[string]any{"color": "red"}).
+//
+// When the given value is nil, the returned ListOption is a no-op.
+func WithJSONBContains(columnName string, value any) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if value == nil {
+			return sb
+		}
+		return sb.Where(jsonbContains{column: columnName, value: value})
+	})
+}
+
+type jsonbContains struct {
+	column string
+	value  any
+}
+
+func (c jsonbContains) ToSql() (string, []any, error) {
+	raw, err := json.Marshal(c.value)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal value of %s: %w", c.column, err)
+	}
+	return c.column + " @> ?::jsonb", []any{string(raw)}, nil
+}
+
+// WithJSONBKeyExists returns a ListOption that filters the rows whose jsonb
+// column has the top-level key, i.e. column ? key.
+//
+// When the given key is empty, the returned ListOption is a no-op.
+func WithJSONBKeyExists(columnName, key string) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if key == "" {
+			return sb
+		}
+		// "??" is the escaped "?" operator, not a placeholder.
+		return sb.Where(columnName+" ?? ?", key)
+	})
+}
+
+// WithJSONBPath returns a ListOption that filters the rows whose jsonb column
+// has the value at the path, compared as text, i.e. column #>> path = value,
+// e.g. WithJSONBPath("attributes", []string{"size", "width"}, 42).
+//
+// When the given path is empty, or the value is nil, the returned ListOption
+// is a no-op.
+func WithJSONBPath(columnName string, path []string, value any) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if len(path) == 0 || value == nil {
+			return sb
+		}
+		return sb.Where(columnName+" #>> ?::text[] = ?", path, fmt.Sprint(value))
+	})
+}
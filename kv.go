@@ -0,0 +1,169 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// KVSchema is the DDL of the table backing KV. Run it along with the
+// migrations of the application.
+const KVSchema = `
+CREATE TABLE IF NOT EXISTS kv (
+	key        text PRIMARY KEY,
+	value      jsonb NOT NULL,
+	expires_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS kv_expires_at_idx ON kv (expires_at) WHERE expires_at IS NOT NULL;
+`
+
+// kvAlive is the condition of the keys not expired.
+const kvAlive = "(kv.expires_at IS NULL OR kv.expires_at > now())"
+
+// KV is a key-value store backed by the kv table (see KVSchema). The values
+// are stored as JSON.
+//
+// Usage: configuration and coordination data that doesn't merit its own schema.
+//
+// Example:
+//
+//	err := pg.KV.Set(ctx, "maintenance", map[string]any{"enabled": true}, time.Hour)
+//	var m Maintenance
+//	found, err := pg.KV.Get(ctx, "maintenance", &m)
+var KV = new(KVStore)
+
+// KVStore implements the key-value store. See KV.
+type KVStore struct{}
+
+// Get decodes the value of the key into dest. Returns false if the key is
+// not found or expired.
+func (s *KVStore) Get(ctx context.Context, key string, dest any) (bool, error) {
+	var raw []byte
+	err := s.queryRow(ctx, func(q Querier) error {
+		return q.QueryRow(ctx, "SELECT value FROM kv WHERE key = $1 AND "+kvAlive, key).Scan(&raw)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("get key %q: %w", key, err)
+	}
+	return true, json.Unmarshal(raw, dest)
+}
+
+// Set sets the value of the key. The key expires after ttl, zero ttl means
+// never.
+func (s *KVStore) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal value: %w", err)
+	}
+	_, err = s.exec(ctx, `INSERT INTO kv (key, value, expires_at) VALUES ($1, $2, now() + $3::float8 * interval '1 second')
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`, key, raw, ttlSeconds(ttl))
+	if err != nil {
+		return fmt.Errorf("set key %q: %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes the key. Returns false if the key doesn't exist.
+func (s *KVStore) Delete(ctx context.Context, key string) (bool, error) {
+	n, err := s.exec(ctx, "DELETE FROM kv WHERE key = $1 AND "+kvAlive, key)
+	if err != nil {
+		return false, fmt.Errorf("delete key %q: %w", key, err)
+	}
+	return n > 0, nil
+}
+
+// CompareAndSwap sets the value of the key to newValue only if its current
+// value equals (as JSON) oldValue. A nil oldValue means the key must not
+// exist. Returns whether the value was swapped. The key expires after ttl,
+// zero ttl means never.
+func (s *KVStore) CompareAndSwap(ctx context.Context, key string, oldValue, newValue any, ttl time.Duration) (bool, error) {
+	newRaw, err := json.Marshal(newValue)
+	if err != nil {
+		return false, fmt.Errorf("marshal value: %w", err)
+	}
+
+	var n int64
+	if oldValue == nil {
+		n, err = s.exec(ctx, `INSERT INTO kv (key, value, expires_at) VALUES ($1, $2, now() + $3::float8 * interval '1 second')
+			ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+			WHERE NOT `+kvAlive, key, newRaw, ttlSeconds(ttl))
+	} else {
+		var oldRaw []byte
+		if oldRaw, err = json.Marshal(oldValue); err != nil {
+			return false, fmt.Errorf("marshal value: %w", err)
+		}
+		n, err = s.exec(ctx, `UPDATE kv SET value = $3, expires_at = now() + $4::float8 * interval '1 second'
+			WHERE key = $1 AND value = $2::jsonb AND `+kvAlive, key, oldRaw, newRaw, ttlSeconds(ttl))
+	}
+	if err != nil {
+		return false, fmt.Errorf("swap key %q: %w", key, err)
+	}
+	return n > 0, nil
+}
+
+// TTL returns the remaining time to live of the key, zero means the key
+// never expires. Returns false if the key is not found or expired.
+func (s *KVStore) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	var seconds *float64
+	err := s.queryRow(ctx, func(q Querier) error {
+		return q.QueryRow(ctx, "SELECT EXTRACT(EPOCH FROM expires_at - now())::float8 FROM kv WHERE key = $1 AND "+kvAlive, key).Scan(&seconds)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("get ttl of key %q: %w", key, err)
+	}
+	if seconds == nil {
+		return 0, true, nil
+	}
+	return time.Duration(*seconds * float64(time.Second)), true, nil
+}
+
+// PurgeExpired deletes the expired keys. Returns the number of deleted keys.
+func (s *KVStore) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.exec(ctx, "DELETE FROM kv WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("purge expired keys: %w", err)
+	}
+	return n, nil
+}
+
+func (s *KVStore) exec(ctx context.Context, sql string, args ...any) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *KVStore) queryRow(ctx context.Context, fn func(Querier) error) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(q)
+}
+
+// ttlSeconds converts ttl to seconds, nil (NULL) for zero ttl.
+func ttlSeconds(ttl time.Duration) *float64 {
+	if ttl <= 0 {
+		return nil
+	}
+	seconds := ttl.Seconds()
+	return &seconds
+}
@@ -2,11 +2,15 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
-	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/jackc/pgx/v5"
 	"github.com/lann/builder"
 )
 
@@ -19,7 +23,50 @@ import (
 //	var users []*User
 //	pagination, err := pg.List(ctx, users, pg.SQL.Select("*").From("users"))
 func List[T any](ctx context.Context, vs T, query sq.SelectBuilder, opts ...ListOption) (*OffsetPagination, error) {
-	filteringOpts, pagingOpts, sortingOpts := CategorizedListOptions(opts...)
+	return list(ctx, &vs, query, opts...)
+}
+
+// ListResult is a page of items along with the pagination info. It's
+// marshaled to JSON as {"items": [...], "pagination": {...}}.
+type ListResult[T any] struct {
+	Items      []T
+	Pagination *OffsetPagination
+}
+
+// MarshalJSON implements json.Marshaler. No items are marshaled as an empty
+// array rather than null.
+func (r ListResult[T]) MarshalJSON() ([]byte, error) {
+	items := r.Items
+	if items == nil {
+		items = []T{}
+	}
+	return json.Marshal(struct {
+		Items      []T               `json:"items"`
+		Pagination *OffsetPagination `json:"pagination"`
+	}{items, r.Pagination})
+}
+
+// ListItems works like List, but returns the items along with the pagination
+// info, ready to be written as the response of an API.
+//
+// Example:
+//
+//	result, err := pg.ListItems[*User](ctx, pg.SQL.Select("*").From("users"), pg.WithOffsetPagination(page))
+//	json.NewEncoder(w).Encode(result)
+func ListItems[T any](ctx context.Context, query sq.SelectBuilder, opts ...ListOption) (*ListResult[T], error) {
+	result := new(ListResult[T])
+	pagination, err := list(ctx, &result.Items, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result.Pagination = pagination
+	return result, nil
+}
+
+// list runs the query of List, scanning the rows into dest, a pointer to a
+// slice.
+func list(ctx context.Context, dest any, query sq.SelectBuilder, opts ...ListOption) (*OffsetPagination, error) {
+	filteringOpts, pagingOpts, sortingOpts := CategorizedListOptions(withDefaultListOptions(opts)...)
 
 	if len(pagingOpts) == 0 {
 		pagingOpts = []ListOption{WithOffsetPagination(NewOffsetPagination(20))}
@@ -27,24 +74,57 @@ func List[T any](ctx context.Context, vs T, query sq.SelectBuilder, opts ...List
 	if len(pagingOpts) > 1 {
 		return nil, errors.New("only one pagination option is allowed")
 	}
+	if strict {
+		if err := checkStrict(query, filteringOpts, sortingOpts); err != nil {
+			return nil, err
+		}
+	}
 	pagination := pagingOpts[0].(*withOffsetPaginationOption).page
+	if err := pagination.Validate(); err != nil {
+		return nil, err
+	}
 
+	var countOpt ListOption
 	for _, opt := range filteringOpts {
+		switch opt.(type) {
+		case *withoutCountOption, *withWindowCountOption, *withEstimatedCountOption:
+			if countOpt != nil {
+				return nil, errors.New("only one count option is allowed")
+			}
+			countOpt = opt
+		}
 		query = opt.Apply(query)
 	}
 
-	sqlstr, args, err := toCountQuery(query).ToSql()
+	q, release, err := querier(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("assemble count query: %w", err)
+		return nil, err
 	}
+	defer release()
 
 	var total int64
-	if err := DB().QueryRow(ctx, sqlstr, args...).Scan(&total); err != nil {
-		return nil, fmt.Errorf("count records: %w", err)
+	switch countOpt.(type) {
+	case *withoutCountOption:
+		return pagination, listWithoutCount(ctx, q, dest, query, pagination, sortingOpts)
+	case *withWindowCountOption:
+		return pagination, listWithWindowCount(ctx, q, dest, query, pagination, sortingOpts)
+	case *withEstimatedCountOption:
+		if total, err = estimateCount(ctx, q, query); err != nil {
+			return nil, err
+		}
+	default:
+		sqlstr, args, err := toCountQuery(query).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("assemble count query: %w", err)
+		}
+		if err := q.QueryRow(ctx, sqlstr, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("count records: %w", err)
+		}
 	}
 
 	pagination.SetCountRecords(total)
-	if pagination.CountRecords == 0 || pagination.Page > pagination.CountPages {
+	_, estimated := countOpt.(*withEstimatedCountOption)
+	if !estimated && (pagination.CountRecords == 0 || pagination.Page > pagination.CountPages) {
 		return pagination, nil // skip running query
 	}
 
@@ -55,17 +135,171 @@ func List[T any](ctx context.Context, vs T, query sq.SelectBuilder, opts ...List
 		query = opt.Apply(query)
 	}
 
-	sqlstr, args, err = query.ToSql()
+	sqlstr, args, err := query.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("assemble query: %w", err)
 	}
 
-	err = pgxscan.Select(ctx, DB(), &vs, sqlstr, args...)
+	if enc, ok := dest.(rowEncoder); ok {
+		_, err = enc.encodeRows(ctx, q, sqlstr, args, -1, nil)
+		return pagination, err
+	}
+	err = scanAPI(dest).Select(ctx, q, dest, sqlstr, args...)
 	return pagination, err
 }
 
+// listWithoutCount fetches one more row than the page size into dest, to tell
+// whether there is a next page, and trims it.
+func listWithoutCount(ctx context.Context, q Querier, dest any, query sq.SelectBuilder, pagination *OffsetPagination, sortingOpts []ListOption) error {
+	for _, opt := range sortingOpts {
+		query = opt.Apply(query)
+	}
+	limit := pagination.Limit()
+	sqlstr, args, err := query.Limit(uint64(limit) + 1).Offset(uint64(pagination.Offset())).ToSql()
+	if err != nil {
+		return fmt.Errorf("assemble query: %w", err)
+	}
+	if enc, ok := dest.(rowEncoder); ok {
+		fetched, err := enc.encodeRows(ctx, q, sqlstr, args, limit, nil)
+		if err != nil {
+			return err
+		}
+		pagination.SetCountRecords(pagination.Offset() + fetched)
+		return nil
+	}
+	if err := scanAPI(dest).Select(ctx, q, dest, sqlstr, args...); err != nil {
+		return err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	fetched := int64(rows.Len())
+	if fetched > limit {
+		rows.Set(rows.Slice(0, int(limit)))
+	}
+	pagination.SetCountRecords(pagination.Offset() + fetched)
+	return nil
+}
+
+// windowCountColumn is the column of the total count selected by
+// listWithWindowCount.
+const windowCountColumn = "__total"
+
+// listWithWindowCount fetches the rows of the page into dest along with the
+// total count, selected by a window function.
+func listWithWindowCount(ctx context.Context, q Querier, dest any, query sq.SelectBuilder, pagination *OffsetPagination, sortingOpts []ListOption) error {
+	pageQuery := query.Column("COUNT(*) OVER() AS " + windowCountColumn)
+	for _, opt := range sortingOpts {
+		pageQuery = opt.Apply(pageQuery)
+	}
+	sqlstr, args, err := WithOffsetPagination(pagination).Apply(pageQuery).ToSql()
+	if err != nil {
+		return fmt.Errorf("assemble query: %w", err)
+	}
+
+	total := int64(-1)
+	readTotal := func(rows pgx.Rows) error {
+		values, err := rows.Values()
+		if err != nil {
+			return fmt.Errorf("read total count: %w", err)
+		}
+		for i, field := range rows.FieldDescriptions() {
+			if field.Name == windowCountColumn {
+				total, _ = values[i].(int64)
+			}
+		}
+		return nil
+	}
+	if enc, ok := dest.(rowEncoder); ok {
+		if _, err := enc.encodeRows(ctx, q, sqlstr, args, -1, readTotal); err != nil {
+			return err
+		}
+	} else {
+		rows, err := q.Query(ctx, sqlstr, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		items := reflect.ValueOf(dest).Elem()
+		// Ignore the total count column.
+		scanner := scanAPI(dest, dbscan.WithAllowUnknownColumns(true)).NewRowScanner(rows)
+		for rows.Next() {
+			item, elem := newRowDest(items.Type().Elem())
+			if err := scanner.Scan(item.Interface()); err != nil {
+				return fmt.Errorf("scan row: %w", err)
+			}
+			if err := readTotal(rows); err != nil {
+				return err
+			}
+			items.Set(reflect.Append(items, elem))
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	// No rows, the page is past the end unless it's the first one.
+	if total < 0 {
+		total = 0
+		if pagination.Page > 1 {
+			sqlstr, args, err := toCountQuery(query).ToSql()
+			if err != nil {
+				return fmt.Errorf("assemble count query: %w", err)
+			}
+			if err := q.QueryRow(ctx, sqlstr, args...).Scan(&total); err != nil {
+				return fmt.Errorf("count records: %w", err)
+			}
+		}
+	}
+	pagination.SetCountRecords(total)
+	return nil
+}
+
+// estimateCount returns the number of rows the planner estimates the query
+// returns, i.e. the "Plan Rows" of the top node of its plan.
+func estimateCount(ctx context.Context, q Querier, query sq.SelectBuilder) (int64, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble query: %w", err)
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	var plan []byte
+	if err := q.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sqlstr, args...).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("estimate count: %w", err)
+	}
+	if err := json.Unmarshal(plan, &plans); err != nil || len(plans) == 0 {
+		return 0, fmt.Errorf("estimate count: unexpected plan %s", plan)
+	}
+	return int64(plans[0].Plan.Rows), nil
+}
+
+// toCountQuery turns the query into the one counting its rows. The grouped
+// and the distinct queries are counted as subqueries, since replacing their
+// columns with COUNT(*) would count the rows per group instead.
 func toCountQuery(query sq.SelectBuilder) sq.SelectBuilder {
+	if isGroupedQuery(query) {
+		return SQL.Select("COUNT(*)").FromSelect(query, "t")
+	}
 	countQuery := builder.Delete(query, "Columns").(sq.SelectBuilder)
 	countQuery = countQuery.Columns("COUNT(*)")
 	return countQuery
 }
+
+// isGroupedQuery tells whether the query has GROUP BY or DISTINCT (ON).
+func isGroupedQuery(query sq.SelectBuilder) bool {
+	if groupBys, ok := builder.Get(query, "GroupBys"); ok && len(groupBys.([]string)) > 0 {
+		return true
+	}
+	if options, ok := builder.Get(query, "Options"); ok {
+		for _, option := range options.([]string) {
+			if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(option)), "DISTINCT") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// EncodeList works like ListItems, but writes each row to the encoder as soon
+// as it's scanned rather than collecting the page, followed by the pagination
+// info as {"pagination": {...}}, i.e. a stream of JSON values, one per line.
+// Thus the memory is bounded by a row rather than a page. The rows written
+// before an error are not taken back.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "application/x-ndjson")
+//	err := pg.EncodeList[*User](ctx, json.NewEncoder(w), pg.SQL.Select("*").From("users"), pg.WithOffsetPagination(page))
+func EncodeList[T any](ctx context.Context, enc *json.Encoder, query sq.SelectBuilder, opts ...ListOption) error {
+	pagination, err := list(ctx, &listEncoder[T]{enc: enc}, query, opts...)
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(map[string]*OffsetPagination{"pagination": pagination}); err != nil {
+		return fmt.Errorf("encode pagination: %w", err)
+	}
+	return nil
+}
+
+// rowEncoder is the dest of list encoding the rows rather than scanning them
+// into a slice, see EncodeList.
+type rowEncoder interface {
+	// encodeRows runs the query and encodes at most max (all if negative) of
+	// its rows, calling each (optional) on every row, the unknown columns are
+	// ignored then. Returns the number of the rows read.
+	encodeRows(ctx context.Context, q Querier, sqlstr string, args []any, max int64, each func(pgx.Rows) error) (int64, error)
+}
+
+type listEncoder[T any] struct {
+	enc *json.Encoder
+}
+
+func (e *listEncoder[T]) encodeRows(ctx context.Context, q Querier, sqlstr string, args []any, max int64, each func(pgx.Rows) error) (int64, error) {
+	rows, err := q.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var opts []dbscan.APIOption
+	if each != nil {
+		opts = append(opts, dbscan.WithAllowUnknownColumns(true))
+	}
+	scanner := scanAPI(new(T), opts...).NewRowScanner(rows)
+	var n int64
+	for rows.Next() {
+		n++
+		if max >= 0 && n > max {
+			continue
+		}
+		v, err := scanRow[T](scanner)
+		if err != nil {
+			return n, fmt.Errorf("scan row: %w", err)
+		}
+		if each != nil {
+			if err := each(rows); err != nil {
+				return n, err
+			}
+		}
+		if err := e.enc.Encode(v); err != nil {
+			return n, fmt.Errorf("encode row: %w", err)
+		}
+	}
+	return n, rows.Err()
+}
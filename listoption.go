@@ -1,6 +1,11 @@
 package pg
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
 	sq "github.com/Masterminds/squirrel"
 )
 
@@ -16,6 +21,45 @@ func (f ListOptionFunc) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
 	return f(sb)
 }
 
+var defaultListOptions []ListOption
+
+// SetDefaultListOptions sets the options applied to every List call before the
+// options of the call, e.g. always excluding the soft-deleted rows, enforced in
+// one place rather than in every repository. A default pagination option is
+// overridden by the one of the call. SetDefaultListOptions is not safe to call
+// concurrently with running queries, call it at initialization.
+//
+// Example:
+//
+//	pg.SetDefaultListOptions(pg.ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+//		return sb.Where("deleted_at IS NULL")
+//	}))
+func SetDefaultListOptions(opts ...ListOption) {
+	defaultListOptions = opts
+}
+
+// withDefaultListOptions prepends the default options to opts.
+func withDefaultListOptions(opts []ListOption) []ListOption {
+	if len(defaultListOptions) == 0 {
+		return opts
+	}
+	hasPaging := false
+	for _, opt := range opts {
+		if IsPaginationOption(opt) {
+			hasPaging = true
+			break
+		}
+	}
+	merged := make([]ListOption, 0, len(defaultListOptions)+len(opts))
+	for _, opt := range defaultListOptions {
+		if hasPaging && IsPaginationOption(opt) {
+			continue
+		}
+		merged = append(merged, opt)
+	}
+	return append(merged, opts...)
+}
+
 // With returns a ListOption that applies the given condition to the query.
 //
 // When the given value is empty, the returned ListOption is a no-op.
@@ -26,7 +70,7 @@ func (f ListOptionFunc) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
 // When the value is a slice, it will be expanded to a list of OR conditions.
 // Which is equivalent to an IN statement.
 func With[T any](columnName string, value ...T) ListOption {
-	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
 		// noop
 		if len(value) == 0 {
 			return sb
@@ -48,7 +92,7 @@ func With[T any](columnName string, value ...T) ListOption {
 
 // Without works like With, but it negates the condition. See With for more details.
 func Without[T any](columnName string, value ...T) ListOption {
-	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
 		// noop
 		if len(value) == 0 {
 			return sb
@@ -68,19 +112,331 @@ func Without[T any](columnName string, value ...T) ListOption {
 	})
 }
 
+// WithGreaterThan returns a ListOption that filters the rows whose column is
+// greater than the given value, e.g. WithGreaterThan("price", 100).
+//
+// When the given value is zero, the returned ListOption is a no-op, like With.
+func WithGreaterThan[T any](columnName string, value T) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if isZeroValue(value) {
+			return sb
+		}
+		return sb.Where(sq.Gt{columnName: value})
+	})
+}
+
+// WithLessThan works like WithGreaterThan, but filters the rows whose column is
+// less than the given value.
+func WithLessThan[T any](columnName string, value T) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if isZeroValue(value) {
+			return sb
+		}
+		return sb.Where(sq.Lt{columnName: value})
+	})
+}
+
+// WithBetween returns a ListOption that filters the rows whose column is
+// between min and max, inclusively.
+//
+// A zero bound is open, e.g. WithBetween("price", 100, 0) is price >= 100.
+// When both bounds are zero, the returned ListOption is a no-op.
+func WithBetween[T any](columnName string, min, max T) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if !isZeroValue(min) {
+			sb = sb.Where(sq.GtOrEq{columnName: min})
+		}
+		if !isZeroValue(max) {
+			sb = sb.Where(sq.LtOrEq{columnName: max})
+		}
+		return sb
+	})
+}
+
+// WithTimeRange returns a ListOption that filters the rows whose column is in
+// the half-open time range [from, to), which chains the adjacent ranges
+// without overlaps, e.g. the days of a report.
+//
+// A zero bound is open. When both bounds are zero, the returned ListOption is
+// a no-op.
+func WithTimeRange(columnName string, from, to time.Time) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		if !from.IsZero() {
+			sb = sb.Where(sq.GtOrEq{columnName: from})
+		}
+		if !to.IsZero() {
+			sb = sb.Where(sq.Lt{columnName: to})
+		}
+		return sb
+	})
+}
+
+// WithNull returns a ListOption that filters the rows whose column is NULL,
+// e.g. WithNull("deleted_at").
+func WithNull(columnName string) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Where(sq.Eq{columnName: nil})
+	})
+}
+
+// WithNotNull returns a ListOption that filters the rows whose column is NOT
+// NULL, e.g. WithNotNull("verified_at").
+func WithNotNull(columnName string) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Where(sq.NotEq{columnName: nil})
+	})
+}
+
+// WithRawWhere returns a ListOption that adds the raw SQL condition with its
+// args to the query, e.g. the exotic operators or the subselects not covered
+// by the other options. It's a filtering option (see CategorizedListOptions),
+// thus it also applies to the count query of List. The placeholders are "?".
+//
+// Example:
+//
+//	pg.WithRawWhere("id IN (SELECT user_id FROM team_members WHERE team_id = ?)", teamID)
+func WithRawWhere(sql string, args ...any) ListOption {
+	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Where(sql, args...)
+	})
+}
+
+// WithIn returns a ListOption that filters the rows whose column is in the
+// result of the subquery, e.g. the users who have placed an order:
+//
+//	pg.WithIn("id", pg.SQL.Select("user_id").From("orders").Where(sq.Gt{"total": 100}))
+//
+// The placeholders of the subquery are renumbered along with the query.
+func WithIn(columnName string, sub sq.SelectBuilder) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Where(sq.Expr(columnName+" IN (?)", sub.PlaceholderFormat(sq.Question)))
+	})
+}
+
+// WithExists returns a ListOption that filters the rows for which the
+// (correlated) subquery returns any rows, e.g. the users who have an order:
+//
+//	pg.WithExists(pg.SQL.Select("1").From("orders o").Where("o.user_id = users.id"))
+//
+// Use Not(WithExists(...)) for NOT EXISTS. The placeholders of the subquery
+// are renumbered along with the query.
+func WithExists(sub sq.SelectBuilder) ListOption {
+	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+		return sb.Where(sq.Expr("EXISTS (?)", sub.PlaceholderFormat(sq.Question)))
+	})
+}
+
+// WithPrefix returns a ListOption that filters the rows whose column starts
+// with the given value, case-sensitively (LIKE). The wildcards (% and _) in
+// the value are escaped, thus it's safe for the user input.
+//
+// When the given value is empty, the returned ListOption is a no-op.
+func WithPrefix(columnName, value string) ListOption {
+	return withLike(columnName, escapeLike(value)+"%", false)
+}
+
+// WithSuffix works like WithPrefix, but filters the rows whose column ends
+// with the given value.
+func WithSuffix(columnName, value string) ListOption {
+	return withLike(columnName, "%"+escapeLike(value), false)
+}
+
+// WithContains works like WithPrefix, but filters the rows whose column
+// contains the given value.
+func WithContains(columnName, value string) ListOption {
+	return withLike(columnName, "%"+escapeLike(value)+"%", false)
+}
+
+// WithILike works like WithContains, but case-insensitively (ILIKE), e.g. for
+// the search-as-you-type endpoints.
+func WithILike(columnName, value string) ListOption {
+	return withLike(columnName, "%"+escapeLike(value)+"%", true)
+}
+
+func withLike(columnName, pattern string, insensitive bool) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if strings.Trim(pattern, "%") == "" {
+			return sb
+		}
+		if insensitive {
+			return sb.Where(sq.ILike{columnName: pattern})
+		}
+		return sb.Where(sq.Like{columnName: pattern})
+	})
+}
+
+// likeEscaper escapes the wildcards of LIKE with the default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+func isZeroValue(v any) bool {
+	rv := reflect.ValueOf(v)
+	return !rv.IsValid() || rv.IsZero()
+}
+
+// columnFilterOption is a filtering option on a column, which is checked in
+// the strict mode. See SetStrict.
+type columnFilterOption struct {
+	column string
+	apply  ListOptionFunc
+}
+
+func (o *columnFilterOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	return o.apply(sb)
+}
+
+func filterOn(column string, apply ListOptionFunc) ListOption {
+	return &columnFilterOption{column, apply}
+}
+
+type withoutCountOption struct{}
+
+func (o *withoutCountOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	return sb
+}
+
+// WithoutCount returns a ListOption that makes List skip the COUNT(*) query,
+// which is expensive on large tables, and fetch one more row than the page
+// size instead to tell whether there is a next page. The pagination info is
+// partial: CountRecords (thus CountPages) is a lower bound, i.e. the records
+// up to the end of the page plus one if there is a next page, while HasNext is
+// exact.
+func WithoutCount() ListOption {
+	return &withoutCountOption{}
+}
+
+type withWindowCountOption struct{}
+
+func (o *withWindowCountOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	return sb
+}
+
+// WithWindowCount returns a ListOption that makes List fetch the rows and the
+// total count in one query, using a window function (COUNT(*) OVER()),
+// instead of running a COUNT(*) query first. It saves a round trip, while the
+// database still counts all the matching rows.
+func WithWindowCount() ListOption {
+	return &withWindowCountOption{}
+}
+
+type withEstimatedCountOption struct{}
+
+func (o *withEstimatedCountOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	return sb
+}
+
+// WithEstimatedCount returns a ListOption that makes List derive the count of
+// the records from the estimate of the planner, i.e. EXPLAIN of the query,
+// instead of an exact COUNT(*). The estimate relies on the statistics of the
+// tables (pg_class.reltuples and friends, refreshed by ANALYZE), thus it can
+// be off, but it's cheap on huge tables, where the exact count dominates the
+// time of the query.
+func WithEstimatedCount() ListOption {
+	return &withEstimatedCountOption{}
+}
+
 type withSortByOption struct {
-	columnName string
+	columnName string // or an expression with the placeholders of args, empty for a no-op
 	direction  string // "asc" or "desc"
+	nulls      string // "", "first" or "last"
+	args       []any
 }
 
 func (o *withSortByOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
-	return sb.OrderBy(o.columnName + " " + o.direction)
+	if o.columnName == "" {
+		return sb
+	}
+	clause, err := o.clause()
+	if err != nil {
+		return sb.OrderByClause(errCondition{err})
+	}
+	return sb.OrderByClause(clause, o.args...)
+}
+
+// clause validates the direction and the nulls ordering, and returns the
+// ORDER BY expression.
+func (o *withSortByOption) clause() (string, error) {
+	clause := o.columnName
+	switch direction := strings.ToLower(o.direction); direction {
+	case "":
+	case "asc", "desc":
+		clause += " " + direction
+	default:
+		return "", fmt.Errorf("%w: direction %q of %s", ErrInvalidSort, o.direction, o.columnName)
+	}
+	switch nulls := strings.ToLower(o.nulls); nulls {
+	case "":
+	case NullsFirst, NullsLast:
+		clause += " NULLS " + strings.ToUpper(nulls)
+	default:
+		return "", fmt.Errorf("%w: nulls %q of %s", ErrInvalidSort, o.nulls, o.columnName)
+	}
+	return clause, nil
 }
 
 // WithSortBy returns a ListOption that sorts the result by the given column name and sort direction.
 // The direction must be either "asc" or "desc".
 func WithSortBy(columnName, direction string) ListOption {
-	return &withSortByOption{columnName, direction}
+	return &withSortByOption{columnName: columnName, direction: direction}
+}
+
+// The orderings of the NULL values of a Sort.
+const (
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// Sort is a sort key of WithSort. The Direction is either "asc" or "desc"
+// (asc if empty), and Nulls either NullsFirst or NullsLast (the default of
+// PostgreSQL if empty, i.e. last for asc and first for desc).
+type Sort struct {
+	Column    string
+	Direction string
+	Nulls     string
+}
+
+type withSortOption struct {
+	keys []*withSortByOption
+}
+
+func (o *withSortOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	for _, key := range o.keys {
+		sb = key.Apply(sb)
+	}
+	return sb
+}
+
+// WithSort returns a ListOption that sorts the result by the sort keys, in
+// order. An invalid direction or nulls ordering fails the query with
+// ErrInvalidSort.
+//
+// Example:
+//
+//	pg.WithSort(
+//		pg.Sort{Column: "due_at", Direction: "asc", Nulls: pg.NullsLast},
+//		pg.Sort{Column: "id", Direction: "desc"},
+//	)
+func WithSort(sorts ...Sort) ListOption {
+	keys := make([]*withSortByOption, len(sorts))
+	for i, s := range sorts {
+		keys[i] = &withSortByOption{columnName: s.Column, direction: s.Direction, nulls: s.Nulls}
+	}
+	return &withSortOption{keys}
+}
+
+// sortKeysOf returns the sort keys of a sorting option.
+func sortKeysOf(opt ListOption) []*withSortByOption {
+	switch opt := opt.(type) {
+	case *withSortByOption:
+		return []*withSortByOption{opt}
+	case *withSortOption:
+		return opt.keys
+	}
+	return nil
 }
 
 type withOffsetPaginationOption struct {
@@ -106,14 +462,24 @@ func IsPaginationOption(opt ListOption) bool {
 
 // IsSortingOption returns true if the given ListOption is used for limiting the result.
 func IsSortingOption(opt ListOption) bool {
-	_, ok := opt.(*withSortByOption)
-	return ok
+	return sortKeysOf(opt) != nil
+}
+
+// compositeListOption is a ListOption made of the options of different
+// types, categorized apart by CategorizedListOptions, e.g. WithRelevance
+// filtering and sorting.
+type compositeListOption interface {
+	ListOption
+	listOptions() []ListOption
 }
 
 // CategorizedListOptions categorizes the given ListOptions into types of filtering, paging, and sorting.
 func CategorizedListOptions(opts ...ListOption) (filtering, paging, sorting []ListOption) {
 	for _, opt := range opts {
-		if IsPaginationOption(opt) {
+		if composite, ok := opt.(compositeListOption); ok {
+			f, p, s := CategorizedListOptions(composite.listOptions()...)
+			filtering, paging, sorting = append(filtering, f...), append(paging, p...), append(sorting, s...)
+		} else if IsPaginationOption(opt) {
 			paging = append(paging, opt)
 		} else if IsSortingOption(opt) {
 			sorting = append(sorting, opt)
@@ -0,0 +1,91 @@
+package pg
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier runs queries against the database. It's satisfied by *pgxpool.Pool,
+// *pgxpool.Conn, *pgx.Conn and pgx.Tx.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// QueryMiddleware wraps a Querier to inspect or modify the SQL and args of the
+// queries, or to short-circuit them (caching, rewriting, shadow traffic, etc.).
+type QueryMiddleware func(next Querier) Querier
+
+var middlewares []QueryMiddleware
+
+// Use appends middlewares to the pipeline that all Get/List/Exec calls flow
+// through. The middlewares run in the order they are added, i.e. the first one
+// is the outermost. Use is not safe to call concurrently with running queries,
+// call it at initialization, e.g. right after Init.
+func Use(middleware ...QueryMiddleware) {
+	middlewares = append(middlewares, middleware...)
+}
+
+// withMiddlewares wraps the querier with the registered middlewares.
+func withMiddlewares(q Querier) Querier {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		q = middlewares[i](q)
+	}
+	return q
+}
+
+// QuerierFuncs is an adapter to allow the use of ordinary functions as a
+// Querier. The nil functions fall back to the Next querier, so a middleware
+// only needs to implement the methods it cares about.
+type QuerierFuncs struct {
+	Next         Querier
+	ExecFunc     func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryFunc    func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRowFunc func(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (q *QuerierFuncs) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if q.ExecFunc != nil {
+		return q.ExecFunc(ctx, sql, args...)
+	}
+	return q.Next.Exec(ctx, sql, args...)
+}
+
+func (q *QuerierFuncs) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if q.QueryFunc != nil {
+		return q.QueryFunc(ctx, sql, args...)
+	}
+	return q.Next.Query(ctx, sql, args...)
+}
+
+func (q *QuerierFuncs) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if q.QueryRowFunc != nil {
+		return q.QueryRowFunc(ctx, sql, args...)
+	}
+	return q.Next.QueryRow(ctx, sql, args...)
+}
+
+// Rewrite returns a QueryMiddleware that rewrites the SQL and args of every
+// query with the given function.
+func Rewrite(fn func(ctx context.Context, sql string, args []any) (string, []any)) QueryMiddleware {
+	return func(next Querier) Querier {
+		return &QuerierFuncs{
+			Next: next,
+			ExecFunc: func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+				sql, args = fn(ctx, sql, args)
+				return next.Exec(ctx, sql, args...)
+			},
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				sql, args = fn(ctx, sql, args)
+				return next.Query(ctx, sql, args...)
+			},
+			QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+				sql, args = fn(ctx, sql, args)
+				return next.QueryRow(ctx, sql, args...)
+			},
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetRequest describes a single-row lookup executed by MultiGet.
+type GetRequest struct {
+	Dest  any // pointer to the destination, e.g. a pointer to a struct
+	Query sq.SelectBuilder
+}
+
+// batchSender is implemented by the queriers able to send batches, e.g.
+// *pgx.Conn and pgx.Tx.
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// sendBatch sends the batch on the transaction of the context, or the querier
+// of the context, or a connection acquired from the pool, like querier does.
+// Call release after the results are closed.
+func sendBatch(ctx context.Context, batch *pgx.Batch) (results pgx.BatchResults, release func(), err error) {
+	if scope := scopeFrom(ctx); scope != nil {
+		return scope.tx.SendBatch(ctx, batch), func() {}, nil
+	}
+	if q := querierFrom(ctx); q != nil {
+		b, ok := q.(batchSender)
+		if !ok {
+			return nil, nil, fmt.Errorf("querier %T can't send batches", q)
+		}
+		return b.SendBatch(ctx, batch), func() {}, nil
+	}
+
+	conn, err := acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn.SendBatch(ctx, batch), conn.Release, nil
+}
+
+// MultiGet executes several single-row lookups in one batch (one round trip)
+// and scans each row into the destination of its request. Returns a slice
+// telling whether each request found a row, in the same order as reqs.
+//
+// The batch is sent as a whole, thus it doesn't flow through the middlewares
+// registered by Use.
+//
+// Usage: assembling multiple entities of different models on an aggregate endpoint.
+//
+// Example:
+//
+//	var user = new(User)
+//	var org = new(Organization)
+//	found, err := pg.MultiGet(ctx,
+//		pg.GetRequest{Dest: user, Query: pg.SQL.Select("*").From("users").Where(sq.Eq{"id": 1})},
+//		pg.GetRequest{Dest: org, Query: pg.SQL.Select("*").From("organizations").Where(sq.Eq{"id": 2})},
+//	)
+func MultiGet(ctx context.Context, reqs ...GetRequest) ([]bool, error) {
+	batch := new(pgx.Batch)
+	for i, req := range reqs {
+		sqlstr, args, err := req.Query.ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("assemble query #%d: %w", i, err)
+		}
+		batch.Queue(sqlstr, args...)
+	}
+
+	results, release, err := sendBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer results.Close()
+
+	found := make([]bool, len(reqs))
+	for i, req := range reqs {
+		rows, err := results.Query()
+		if err != nil {
+			return nil, fmt.Errorf("query #%d: %w", i, err)
+		}
+		err = pgxscan.ScanOne(req.Dest, rows)
+		if pgxscan.NotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("scan #%d: %w", i, err)
+		}
+		found[i] = true
+	}
+	return found, results.Close()
+}
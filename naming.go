@@ -0,0 +1,159 @@
+package pg
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+// NamingStrategy maps the name of a struct field to the name of its column,
+// for the fields without a "db" tag. It's used by both the scanning of the
+// rows and the writing of the structs (Insert, Upsert, Update, BulkInsert).
+type NamingStrategy func(fieldName string) string
+
+var (
+	// SnakeCase maps the field names to snake case, e.g. UserID to user_id.
+	// It's the default.
+	SnakeCase NamingStrategy = dbscan.SnakeCaseMapper
+
+	// CamelCase maps the field names to lower camel case, e.g. UserID to
+	// userId, URLPath to urlPath.
+	CamelCase NamingStrategy = camelCase
+)
+
+// ModelNaming is implemented by the models overriding the global naming
+// strategy, e.g. the ones of a legacy schema.
+//
+// Example:
+//
+//	func (LegacyUser) NamingStrategy() pg.NamingStrategy { return pg.CamelCase }
+type ModelNaming interface {
+	NamingStrategy() NamingStrategy
+}
+
+var naming = struct {
+	sync.RWMutex
+	strategy NamingStrategy
+	api      *pgxscan.API
+	models   sync.Map // reflect.Type to *pgxscan.API
+}{strategy: SnakeCase, api: pgxscan.DefaultAPI}
+
+// SetNamingStrategy sets the global naming strategy, SnakeCase by default.
+// Call it at initialization.
+//
+// Example:
+//
+//	pg.SetNamingStrategy(pg.CamelCase)
+//	pg.SetNamingStrategy(strings.ToLower) // e.g. UserID to userid
+func SetNamingStrategy(strategy NamingStrategy) {
+	naming.Lock()
+	defer naming.Unlock()
+	naming.strategy = strategy
+	naming.api = newScanAPI(strategy)
+	naming.models = sync.Map{}
+}
+
+// namingOf returns the naming strategy of the model type t, which is either a
+// struct or a pointer to a struct.
+func namingOf(t reflect.Type) NamingStrategy {
+	if t != nil && implementsModelNaming(t) {
+		if t.Implements(modelNamingType) {
+			return reflect.Zero(t).Interface().(ModelNaming).NamingStrategy()
+		}
+		return reflect.New(t).Interface().(ModelNaming).NamingStrategy()
+	}
+	naming.RLock()
+	defer naming.RUnlock()
+	return naming.strategy
+}
+
+// scanAPI returns the API scanning the rows into dest, e.g. a pointer to a
+// struct, or a pointer to a slice of structs, with the naming strategy of its
+// model.
+func scanAPI(dest any, opts ...dbscan.APIOption) *pgxscan.API {
+	t := modelType(reflect.TypeOf(dest))
+	if t == nil || !implementsModelNaming(t) {
+		naming.RLock()
+		defer naming.RUnlock()
+		if len(opts) == 0 {
+			return naming.api
+		}
+		return newScanAPI(naming.strategy, opts...)
+	}
+	if len(opts) > 0 {
+		return newScanAPI(namingOf(t), opts...)
+	}
+	if api, ok := naming.models.Load(t); ok {
+		return api.(*pgxscan.API)
+	}
+	api := newScanAPI(namingOf(t))
+	naming.models.Store(t, api)
+	return api
+}
+
+func newScanAPI(strategy NamingStrategy, opts ...dbscan.APIOption) *pgxscan.API {
+	opts = append([]dbscan.APIOption{dbscan.WithFieldNameMapper(dbscan.NameMapperFunc(strategy))}, opts...)
+	dbscanAPI, err := pgxscan.NewDBScanAPI(opts...)
+	if err != nil {
+		panic(err)
+	}
+	api, err := pgxscan.NewAPI(dbscanAPI)
+	if err != nil {
+		panic(err)
+	}
+	return api
+}
+
+// modelType returns the struct type of the destination t by dereferencing the
+// pointers and the slices, or nil if it's not a struct.
+func modelType(t reflect.Type) reflect.Type {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+var modelNamingType = reflect.TypeOf((*ModelNaming)(nil)).Elem()
+
+func implementsModelNaming(t reflect.Type) bool {
+	return t.Implements(modelNamingType) || reflect.PointerTo(t).Implements(modelNamingType)
+}
+
+func camelCase(name string) string {
+	words := strings.Split(dbscan.SnakeCaseMapper(name), "_")
+	for i := 1; i < len(words); i++ {
+		if words[i] != "" {
+			words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// newRowDest returns a new value of the element type t of a slice to scan a
+// row into, and the element to append. scany doesn't scan into a pointer to a
+// pointer to a struct, so the struct is allocated for a pointer type, e.g.
+// *User.
+func newRowDest(t reflect.Type) (dest, elem reflect.Value) {
+	if t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct {
+		dest = reflect.New(t.Elem())
+		return dest, dest
+	}
+	dest = reflect.New(t)
+	return dest, dest.Elem()
+}
+
+// scanRow scans the current row into a new T, see newRowDest.
+func scanRow[T any](scanner *pgxscan.RowScanner) (T, error) {
+	var v T
+	dest, elem := newRowDest(reflect.TypeOf(&v).Elem())
+	if err := scanner.Scan(dest.Interface()); err != nil {
+		return v, err
+	}
+	return elem.Interface().(T), nil
+}
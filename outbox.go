@@ -0,0 +1,255 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// OutboxSchema is the DDL of the table storing the outbox messages. Run it
+// along with the migrations of the application.
+const OutboxSchema = `
+CREATE TABLE IF NOT EXISTS outbox (
+	id              bigserial PRIMARY KEY,
+	type            text NOT NULL,
+	payload         jsonb NOT NULL,
+	attempts        int NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz NOT NULL DEFAULT now(),
+	locked_until    timestamptz,
+	last_error      text,
+	created_at      timestamptz NOT NULL DEFAULT now(),
+	processed_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (type, next_attempt_at) WHERE processed_at IS NULL;
+`
+
+// The types of the typed outbox messages.
+const (
+	OutboxEmail        = "email"
+	OutboxNotification = "notification"
+)
+
+// OutboxMessage is a message stored in the outbox.
+type OutboxMessage struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LockedUntil   *time.Time      `json:"locked_until"`
+	LastError     *string         `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	ProcessedAt   *time.Time      `json:"processed_at"`
+}
+
+// NamingStrategy implements ModelNaming, the columns of the outbox table are in
+// snake case regardless of the global naming strategy.
+func (OutboxMessage) NamingStrategy() NamingStrategy { return SnakeCase }
+
+// EmailMessage is the payload of an email outbox message.
+type EmailMessage struct {
+	From    string            `json:"from,omitempty"`
+	To      []string          `json:"to"`
+	Cc      []string          `json:"cc,omitempty"`
+	Bcc     []string          `json:"bcc,omitempty"`
+	Subject string            `json:"subject"`
+	Text    string            `json:"text,omitempty"`
+	HTML    string            `json:"html,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Notification is the payload of a notification outbox message, e.g. a push
+// notification or an in-app message.
+type Notification struct {
+	Recipient string         `json:"recipient"`
+	Channel   string         `json:"channel,omitempty"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Emit stores a message of the type in the outbox. Pass the transaction of
+// the business changes as q, so that the message is stored if and only if
+// the changes are committed. The payload is stored as JSON.
+func Emit(ctx context.Context, q Querier, typ string, payload any) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal outbox payload: %w", err)
+	}
+	query := SQL.Insert("outbox").Columns("type", "payload").Values(typ, raw)
+	if _, err := execQuery(ctx, q, query); err != nil {
+		return fmt.Errorf("emit outbox message: %w", err)
+	}
+	return nil
+}
+
+// EmitEmail stores an email message in the outbox. See Emit.
+func EmitEmail(ctx context.Context, q Querier, msg *EmailMessage) error {
+	return Emit(ctx, q, OutboxEmail, msg)
+}
+
+// EmitNotification stores a notification message in the outbox. See Emit.
+func EmitNotification(ctx context.Context, q Querier, n *Notification) error {
+	return Emit(ctx, q, OutboxNotification, n)
+}
+
+// OutboxRelay processes the outbox messages with the handlers registered per
+// type. Each type is processed with its own concurrency limit, so that a slow
+// type doesn't hold up the others. A failed message is retried with
+// exponential backoff, until MaxAttempts. The zero settings mean the defaults
+// of NewOutboxRelay.
+//
+// Example:
+//
+//	relay := pg.NewOutboxRelay()
+//	pg.HandleEmail(relay, 4, func(ctx context.Context, msg *pg.EmailMessage) error {
+//		return mailer.Send(ctx, msg)
+//	})
+//	go relay.Run(ctx)
+type OutboxRelay struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Lease       time.Duration // the max duration to process a message
+
+	handlers map[string]*outboxHandler
+}
+
+type outboxHandler struct {
+	concurrency int
+	fn          func(ctx context.Context, msg *OutboxMessage) error
+}
+
+// NewOutboxRelay creates an OutboxRelay with the default settings: 10
+// attempts, backing off from 10 seconds up to an hour, and a lease of a
+// minute.
+func NewOutboxRelay() *OutboxRelay {
+	return new(OutboxRelay).withDefaults()
+}
+
+// withDefaults returns a copy of the relay whose zero settings are set to the
+// defaults.
+func (r *OutboxRelay) withDefaults() *OutboxRelay {
+	c := *r
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 10
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = 10 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = time.Hour
+	}
+	if c.Lease <= 0 {
+		c.Lease = time.Minute
+	}
+	if c.handlers == nil {
+		c.handlers = make(map[string]*outboxHandler)
+	}
+	return &c
+}
+
+// Handle registers the handler of the messages of the type, processing at
+// most concurrency messages at the same time.
+func (r *OutboxRelay) Handle(typ string, concurrency int, fn func(ctx context.Context, msg *OutboxMessage) error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if r.handlers == nil {
+		r.handlers = make(map[string]*outboxHandler)
+	}
+	r.handlers[typ] = &outboxHandler{concurrency, fn}
+}
+
+// HandleEmail registers the handler of the email messages. See Handle.
+func HandleEmail(r *OutboxRelay, concurrency int, fn func(ctx context.Context, msg *EmailMessage) error) {
+	r.Handle(OutboxEmail, concurrency, decodeOutboxPayload(fn))
+}
+
+// HandleNotification registers the handler of the notification messages.
+// See Handle.
+func HandleNotification(r *OutboxRelay, concurrency int, fn func(ctx context.Context, n *Notification) error) {
+	r.Handle(OutboxNotification, concurrency, decodeOutboxPayload(fn))
+}
+
+func decodeOutboxPayload[T any](fn func(ctx context.Context, payload *T) error) func(ctx context.Context, msg *OutboxMessage) error {
+	return func(ctx context.Context, msg *OutboxMessage) error {
+		payload := new(T)
+		if err := json.Unmarshal(msg.Payload, payload); err != nil {
+			return fmt.Errorf("decode outbox payload: %w", err)
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Run processes the messages until the context is done.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	r = r.withDefaults()
+	var wg sync.WaitGroup
+	for typ, handler := range r.handlers {
+		wg.Add(1)
+		go func(typ string, handler *outboxHandler) {
+			defer wg.Done()
+			r.runType(ctx, typ, handler)
+		}(typ, handler)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (r *OutboxRelay) runType(ctx context.Context, typ string, handler *outboxHandler) {
+	for ctx.Err() == nil {
+		query := SQL.Select("*").From("outbox").
+			Where(sq.Eq{"type": typ, "processed_at": nil}).
+			Where("next_attempt_at <= now()").
+			Where("(locked_until IS NULL OR locked_until < now())").
+			OrderBy("id")
+		messages, err := ClaimRows[*OutboxMessage](ctx, query, uint64(handler.concurrency), func(ub sq.UpdateBuilder) sq.UpdateBuilder {
+			return ub.Set("locked_until", sq.Expr("now() + ? * interval '1 second'", r.Lease.Seconds()))
+		})
+
+		var wg sync.WaitGroup
+		for _, msg := range messages {
+			wg.Add(1)
+			go func(msg *OutboxMessage) {
+				defer wg.Done()
+				r.process(ctx, msg, handler) // retried after the lease on failure
+			}(msg)
+		}
+		wg.Wait()
+
+		if len(messages) == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (r *OutboxRelay) process(ctx context.Context, msg *OutboxMessage, handler *outboxHandler) error {
+	handleErr := handler.fn(ctx, msg)
+	attempts := msg.Attempts + 1
+
+	update := SQL.Update("outbox").
+		Set("attempts", attempts).
+		Set("locked_until", nil).
+		Where(sq.Eq{"id": msg.ID})
+	switch {
+	case handleErr == nil:
+		update = update.Set("processed_at", sq.Expr("now()")).Set("last_error", nil)
+	case attempts >= r.MaxAttempts:
+		// Give up, keep it unprocessed for inspection.
+		update = update.Set("next_attempt_at", sq.Expr("'infinity'::timestamptz")).Set("last_error", handleErr.Error())
+	default:
+		update = update.
+			Set("next_attempt_at", time.Now().Add(backoffDelay(r.BaseBackoff, r.MaxBackoff, attempts))).
+			Set("last_error", handleErr.Error())
+	}
+	_, err := Exec(ctx, update)
+	return err
+}
@@ -8,6 +8,7 @@
 package pg
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -16,14 +17,36 @@ import (
 	"strings"
 )
 
+// The errors of the invalid pagination values, e.g. from the clients. See
+// OffsetPagination.Validate.
+var (
+	ErrInvalidPage    = errors.New("pg: invalid page")
+	ErrInvalidPerPage = errors.New("pg: invalid per_page")
+	ErrPageOutOfRange = errors.New("pg: page out of range")
+	ErrInvalidLimit   = errors.New("pg: invalid limit")
+)
+
 // OffsetPagination holds paging info in offset pagination method.
 type OffsetPagination struct {
 	Page         int64 `json:"page" in:"query=page" `
 	PerPage      int64 `json:"per_page" in:"query=per_page"`
 	CountPages   int64 `json:"count_pages"`
 	CountRecords int64 `json:"count_records"`
+	HasNext      bool  `json:"has_next"`
 
 	defaultPerPage int64
+	maxPerPage     int64
+}
+
+var defaultMaxPageSize int64
+
+// SetDefaultMaxPageSize sets the max page size of the paginations without
+// their own, i.e. the max per page of OffsetPagination (see SetMaxPerPage)
+// and the max limit of SeekPagination (see SetMaxLimit), so that the clients
+// can't dump a table with a huge page. Zero (default) means no limit. Call it
+// at initialization.
+func SetDefaultMaxPageSize(n int64) {
+	defaultMaxPageSize = n
 }
 
 // NewOffsetPagination creates a new `Pagination` with a default page size.
@@ -42,18 +65,64 @@ func (p *OffsetPagination) SetDefaultPerPage(defaultPerPage int64) int64 {
 	return p.defaultPerPage
 }
 
+// SetMaxPerPage sets the max page size, a larger per page is capped to it.
+// Zero means the default of SetDefaultMaxPageSize.
+func (p *OffsetPagination) SetMaxPerPage(maxPerPage int64) int64 {
+	p.maxPerPage = maxPerPage
+	p.normalize()
+	return p.maxPerPage
+}
+
+func (p *OffsetPagination) maxPageSize() int64 {
+	if p.maxPerPage > 0 {
+		return p.maxPerPage
+	}
+	return defaultMaxPageSize
+}
+
 // Limit returns the page size.
 func (p *OffsetPagination) Limit() int64 {
 	p.normalize()
 	return p.PerPage
 }
 
-// Offset returns the size of skipped items of current page.
+// Offset returns the size of skipped items of current page. It saturates at
+// math.MaxInt64 rather than overflowing, see Validate.
 func (p *OffsetPagination) Offset() int64 {
 	p.normalize()
+	if p.Page-1 > math.MaxInt64/p.PerPage {
+		return math.MaxInt64
+	}
 	return (p.Page - 1) * p.PerPage
 }
 
+// Validate checks the page and per page values, e.g. bound from a request:
+// the negative values are rejected with ErrInvalidPage or ErrInvalidPerPage,
+// and the pages whose offset overflows with ErrPageOutOfRange. The zero
+// values mean the defaults.
+func (p *OffsetPagination) Validate() error {
+	if p.Page < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidPage, p.Page)
+	}
+	if p.PerPage < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidPerPage, p.PerPage)
+	}
+	perPage := p.PerPage
+	if perPage == 0 {
+		perPage = p.defaultPerPage
+	}
+	if perPage <= 0 {
+		perPage = 20
+	}
+	if maxPerPage := p.maxPageSize(); maxPerPage > 0 && perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	if p.Page > 1 && p.Page-1 > math.MaxInt64/perPage {
+		return fmt.Errorf("%w: %d", ErrPageOutOfRange, p.Page)
+	}
+	return nil
+}
+
 // CurrentPage returns the current page index.
 func (p *OffsetPagination) CurrentPage() int64 {
 	p.normalize()
@@ -85,39 +154,86 @@ func (p *OffsetPagination) normalize() {
 		p.PerPage = p.defaultPerPage
 	}
 
+	if maxPerPage := p.maxPageSize(); maxPerPage > 0 && p.PerPage > maxPerPage {
+		p.PerPage = maxPerPage
+	}
+
 	if p.CountRecords <= 0 {
 		p.CountRecords = 0
 	}
 
 	p.CountPages = int64(math.Ceil(float64(p.CountRecords) / float64(p.PerPage)))
+	p.HasNext = p.Page < p.CountPages
 }
 
-// LinkHeader compose a Link Header for the HTTP response.
-// See: https://www.w3.org/wiki/LinkHeader
-// e.g. Link: <https://api.example.com/users?page=1>; rel="first", <https://api.example.com/users?page=2>; rel="next"
-func (p *OffsetPagination) LinkHeader(theURL *url.URL) string {
+// PageLinks is the links to the pages of a pagination, nil if there's no
+// such page.
+type PageLinks struct {
+	First *url.URL
+	Prev  *url.URL
+	Next  *url.URL
+	Last  *url.URL
+}
+
+// String renders the links as a Link header, see LinkHeader.
+func (l PageLinks) String() string {
 	var linkHeaders []string
-	firstLink := theURL.Query()
-	firstLink.Set("page", "1")
-	linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s?%s>; rel="first"`, theURL.Path, firstLink.Encode()))
+	for _, link := range []struct {
+		url *url.URL
+		rel string
+	}{{l.First, "first"}, {l.Prev, "prev"}, {l.Next, "next"}, {l.Last, "last"}} {
+		if link.url != nil {
+			linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s>; rel="%s"`, link.url, link.rel))
+		}
+	}
+	return strings.Join(linkHeaders, ", ")
+}
 
-	if p.Page > 1 {
-		prevLink := theURL.Query()
-		prevLink.Set("page", strconv.FormatInt(p.Page-1, 10))
-		linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s?%s>; rel="prev"`, theURL.Path, prevLink.Encode()))
+// Links returns the links to the first, previous, next and last pages of the
+// URL, e.g. the request URL (see RequestURL), for the Link header or the
+// links of a JSON response. The first and the last links are always present,
+// to page 1 if there're no records. The previous link is absent on the first
+// page, and is the last page past the end. The next link is absent on the
+// last page and past the end. The links are absolute if the URL is,
+// otherwise they're path and query only.
+func (p *OffsetPagination) Links(theURL *url.URL) PageLinks {
+	p.normalize()
+	lastPage := p.CountPages
+	if lastPage < 1 {
+		lastPage = 1
 	}
 
-	if p.Page+1 < p.CountPages {
-		nextLink := theURL.Query()
-		nextLink.Set("page", strconv.FormatInt(p.Page+1, 10))
-		linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s?%s>; rel="next"`, theURL.Path, nextLink.Encode()))
+	links := PageLinks{
+		First: pageURL(theURL, "page", 1),
+		Last:  pageURL(theURL, "page", lastPage),
 	}
+	if p.Page > 1 {
+		prevPage := p.Page - 1
+		if prevPage > lastPage {
+			prevPage = lastPage
+		}
+		links.Prev = pageURL(theURL, "page", prevPage)
+	}
+	if p.Page < p.CountPages {
+		links.Next = pageURL(theURL, "page", p.Page+1)
+	}
+	return links
+}
 
-	lastLink := theURL.Query()
-	lastLink.Set("page", strconv.FormatInt(p.CountPages, 10))
-	linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s?%s>; rel="last"`, theURL.Path, lastLink.Encode()))
+// pageURL returns the URL with the query parameter set to the page.
+func pageURL(theURL *url.URL, param string, page int64) *url.URL {
+	query := theURL.Query()
+	query.Set(param, strconv.FormatInt(page, 10))
+	return linkURL(theURL, query)
+}
 
-	return strings.Join(linkHeaders, ", ")
+// LinkHeader compose a Link Header for the HTTP response.
+// See: https://www.w3.org/wiki/LinkHeader
+// e.g. Link: <https://api.example.com/users?page=1>; rel="first", <https://api.example.com/users?page=2>; rel="next"
+//
+// See Links for the links.
+func (p *OffsetPagination) LinkHeader(theURL *url.URL) string {
+	return p.Links(theURL).String()
 }
 
 // XPaginationHeader compose a text in format: {Page},{Size},{CountPages},{CountRecords}
@@ -136,11 +252,59 @@ func (p *OffsetPagination) String() string {
 	return "OffsetPagination#" + p.XPaginationHeader()
 }
 
-// SetResponseHeaders write paging info headers to the HTTP response.
+// SetResponseHeaders write paging info headers to the HTTP response, with the
+// HeaderWriter set by SetHeaderWriter, StandardHeaders by default.
 func (p *OffsetPagination) SetResponseHeaders(rw http.ResponseWriter, r *http.Request) {
-	// Add Link header for pagination info.
-	rw.Header().Set("Link", p.LinkHeader(r.URL))
-	rw.Header().Set("X-Pagination", p.XPaginationHeader())
+	headerWriter.WriteOffsetHeaders(rw.Header(), r, p)
+}
+
+// OffsetPaginationFromRequest parses the page and per_page query parameters
+// of the request into an OffsetPagination, validated by Validate. The
+// optional defaults are the default page size (20 if absent) and the max page
+// size (see SetMaxPerPage), which caps per_page from the clients.
+//
+// Example:
+//
+//	pagination, err := pg.OffsetPaginationFromRequest(r, 20, 100)
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//	pagination, err = pg.List(ctx, users, query, pg.WithOffsetPagination(pagination))
+func OffsetPaginationFromRequest(r *http.Request, defaults ...int64) (*OffsetPagination, error) {
+	return offsetPaginationFromQuery(r.URL.Query().Get, defaults...)
+}
+
+func offsetPaginationFromQuery(get func(key string) string, defaults ...int64) (*OffsetPagination, error) {
+	var defaultPerPage, maxPerPage int64
+	if len(defaults) > 0 {
+		defaultPerPage = defaults[0]
+	}
+	if len(defaults) > 1 {
+		maxPerPage = defaults[1]
+	}
+
+	p := NewOffsetPagination(defaultPerPage)
+	var err error
+	if p.Page, err = parseIntParam(get("page")); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPage, err)
+	}
+	if p.PerPage, err = parseIntParam(get("per_page")); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPerPage, err)
+	}
+	p.SetMaxPerPage(maxPerPage)
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	p.normalize()
+	return p, nil
+}
+
+func parseIntParam(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
 }
 
 // SeekPagination holds paging info in seek pagination method.
@@ -149,6 +313,7 @@ type SeekPagination struct {
 	cursor string
 
 	defaultLimit int64
+	maxLimit     int64
 }
 
 // NewSeekPagination creates a new SeekPagination with default limit value.
@@ -168,8 +333,17 @@ func (p *SeekPagination) SetLimit(newLimit int64) int64 {
 	return p.limit
 }
 
+// SetMaxLimit sets the max limit, a larger limit is capped to it. Zero means
+// the default of SetDefaultMaxPageSize.
+func (p *SeekPagination) SetMaxLimit(maxLimit int64) int64 {
+	p.maxLimit = maxLimit
+	p.normalize()
+	return p.maxLimit
+}
+
 // Limit returns a valid limit (>0) number.
 func (p *SeekPagination) Limit() int64 {
+	p.normalize()
 	return p.limit
 }
 
@@ -184,23 +358,95 @@ func (p *SeekPagination) Cursor() string {
 	return p.cursor
 }
 
+// SetCursorValues encodes the keyset values into the cursor. See EncodeCursor.
+func (p *SeekPagination) SetCursorValues(values ...any) error {
+	cursor, err := EncodeCursor(values...)
+	if err != nil {
+		return err
+	}
+	p.cursor = cursor
+	return nil
+}
+
+// ScanCursor decodes the cursor into dest, the pointers to the keyset values.
+// See DecodeCursor.
+func (p *SeekPagination) ScanCursor(dest ...any) error {
+	return DecodeCursor(p.cursor, dest...)
+}
+
+// SeekPaginationFromRequest parses the limit and cursor query parameters of
+// the request into a SeekPagination. The optional defaults are the default
+// limit (10 if absent) and the max limit (see SetMaxLimit), which caps limit
+// from the clients.
+// A negative limit is rejected with ErrInvalidLimit, and a cursor not made by
+// EncodeCursor (or not signed, see SetCursorSecret) with ErrInvalidCursor.
+//
+// Example:
+//
+//	pagination, err := pg.SeekPaginationFromRequest(r, 20, 100)
+func SeekPaginationFromRequest(r *http.Request, defaults ...int64) (*SeekPagination, error) {
+	var defaultLimit, maxLimit int64
+	if len(defaults) > 0 {
+		defaultLimit = defaults[0]
+	}
+	if len(defaults) > 1 {
+		maxLimit = defaults[1]
+	}
+
+	query := r.URL.Query()
+	p := NewSeekPagination(defaultLimit)
+	limit, err := parseIntParam(query.Get("limit"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLimit, err)
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLimit, limit)
+	}
+	p.SetMaxLimit(maxLimit)
+	p.SetLimit(limit)
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		if _, err := decodeCursor(cursor); err != nil {
+			return nil, err
+		}
+		p.SetCursor(cursor)
+	}
+	return p, nil
+}
+
 func (p *SeekPagination) normalize() {
 	if p.limit <= 0 {
 		p.limit = p.defaultLimit
 	}
+
+	maxLimit := p.maxLimit
+	if maxLimit <= 0 {
+		maxLimit = defaultMaxPageSize
+	}
+	if maxLimit > 0 && p.limit > maxLimit {
+		p.limit = maxLimit
+	}
+}
+
+// Links returns the link to the next page of the URL, absent if the cursor is
+// empty, i.e. there's no next page. See OffsetPagination.Links.
+func (p *SeekPagination) Links(theURL *url.URL) PageLinks {
+	var links PageLinks
+	if p.Cursor() != "" {
+		query := theURL.Query()
+		query.Set("limit", strconv.FormatInt(p.Limit(), 10))
+		query.Set("cursor", p.Cursor())
+		links.Next = linkURL(theURL, query)
+	}
+	return links
 }
 
 // LinkHeader compose a Link Header for the HTTP response.
 // See: https://www.w3.org/wiki/LinkHeader
+//
+// See Links for the links.
 func (p *SeekPagination) LinkHeader(theURL *url.URL) string {
-	var linkHeaders []string
-
-	nextLink := theURL.Query()
-	nextLink.Set("limit", strconv.FormatInt(p.Limit(), 10))
-	nextLink.Set("cursor", p.Cursor())
-	linkHeaders = append(linkHeaders, fmt.Sprintf(`<%s?%s>; rel="next"`, theURL.Path, nextLink.Encode()))
-
-	return strings.Join(linkHeaders, ", ")
+	return p.Links(theURL).String()
 }
 
 // XPaginationHeader compose a text in format: {Cursor},{Limit}
@@ -213,9 +459,140 @@ func (p *SeekPagination) XPaginationHeader() string {
 	}, ",")
 }
 
-// SetResponseHeaders write paging info headers to the HTTP response.
+// SetResponseHeaders write paging info headers to the HTTP response, with the
+// HeaderWriter set by SetHeaderWriter, StandardHeaders by default.
 func (p *SeekPagination) SetResponseHeaders(rw http.ResponseWriter, r *http.Request) {
+	headerWriter.WriteSeekHeaders(rw.Header(), r, p)
+}
+
+// HeaderWriter writes the paging info headers of the HTTP responses, see
+// SetHeaderWriter.
+type HeaderWriter interface {
+	WriteOffsetHeaders(h http.Header, r *http.Request, p *OffsetPagination)
+	WriteSeekHeaders(h http.Header, r *http.Request, p *SeekPagination)
+}
+
+// StandardHeaders is the default HeaderWriter. It writes the Link and the
+// X-Pagination headers, and the widely used X-Total-Count, X-Page and
+// X-Per-Page headers of the offset paginations. The links are absolute, see
+// RequestURL.
+var StandardHeaders HeaderWriter = standardHeaders{}
+
+var headerWriter = StandardHeaders
+
+// SetHeaderWriter sets the HeaderWriter of SetResponseHeaders, e.g. for
+// another header scheme. Nil means StandardHeaders. Call it at
+// initialization.
+//
+// Example:
+//
+//	type rangeHeaders struct{ pg.HeaderWriter }
+//
+//	func (rangeHeaders) WriteOffsetHeaders(h http.Header, r *http.Request, p *pg.OffsetPagination) {
+//		h.Set("Content-Range", fmt.Sprintf("items %d-%d/%d", p.Offset(), p.Offset()+p.Limit()-1, p.CountRecords))
+//	}
+//
+//	pg.SetHeaderWriter(rangeHeaders{pg.StandardHeaders})
+func SetHeaderWriter(w HeaderWriter) {
+	if w == nil {
+		w = StandardHeaders
+	}
+	headerWriter = w
+}
+
+type standardHeaders struct{}
+
+// linkURL returns the URL with the query, without the user info and the
+// fragment.
+func linkURL(theURL *url.URL, query url.Values) *url.URL {
+	return &url.URL{Scheme: theURL.Scheme, Host: theURL.Host, Path: theURL.Path, RawPath: theURL.RawPath, RawQuery: query.Encode()}
+}
+
+var linkBaseURL *url.URL
+
+// SetLinkBaseURL sets the base URL of the links of the Link headers written
+// by StandardHeaders, e.g. "https://api.example.com/v1" for a service behind
+// a reverse proxy stripping /v1: its scheme and host replace the ones of the
+// requests, and its path prefixes theirs. Empty (default) means the scheme
+// and the host of the requests, see RequestURL. Call it at initialization.
+func SetLinkBaseURL(base string) error {
+	if base == "" {
+		linkBaseURL = nil
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("parse base url: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("base url %q is not absolute", base)
+	}
+	linkBaseURL = u
+	return nil
+}
+
+var trustForwardedHeaders bool
+
+// SetTrustForwardedHeaders sets whether RequestURL honors the
+// X-Forwarded-Proto and X-Forwarded-Host headers. Only enable it behind a
+// reverse proxy setting them, otherwise any client can point the links to a
+// host of its choice. False (default) means they're ignored. Call it at
+// initialization.
+func SetTrustForwardedHeaders(trust bool) {
+	trustForwardedHeaders = trust
+}
+
+// RequestURL returns the absolute URL of the request, as seen by the client:
+// with the base URL set by SetLinkBaseURL if any, otherwise with the scheme
+// and the host of the request, or of the X-Forwarded-Proto and
+// X-Forwarded-Host headers set by the reverse proxies if they're trusted (see
+// SetTrustForwardedHeaders).
+func RequestURL(r *http.Request) *url.URL {
+	u := *r.URL
+	if linkBaseURL != nil {
+		u.Scheme, u.Host = linkBaseURL.Scheme, linkBaseURL.Host
+		u.Path = strings.TrimSuffix(linkBaseURL.Path, "/") + u.Path
+		u.RawPath = ""
+		return &u
+	}
+
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	u.Host = r.Host
+	if !trustForwardedHeaders {
+		return &u
+	}
+	if proto := firstHeaderValue(r, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		u.Scheme = proto
+	}
+	if host := firstHeaderValue(r, "X-Forwarded-Host"); host != "" {
+		u.Host = host
+	}
+	return &u
+}
+
+// firstHeaderValue returns the first value of the comma-separated header,
+// i.e. the one set by the proxy closest to the client.
+func firstHeaderValue(r *http.Request, key string) string {
+	value, _, _ := strings.Cut(r.Header.Get(key), ",")
+	return strings.TrimSpace(value)
+}
+
+func (standardHeaders) WriteOffsetHeaders(h http.Header, r *http.Request, p *OffsetPagination) {
 	// Add Link header for pagination info.
-	rw.Header().Set("Link", p.LinkHeader(r.URL))
-	rw.Header().Set("X-Pagination", p.XPaginationHeader())
+	h.Set("Link", p.LinkHeader(RequestURL(r)))
+	h.Set("X-Pagination", p.XPaginationHeader())
+	h.Set("X-Total-Count", strconv.FormatInt(p.CountRecords, 10))
+	h.Set("X-Page", strconv.FormatInt(p.CurrentPage(), 10))
+	h.Set("X-Per-Page", strconv.FormatInt(p.PageSize(), 10))
+}
+
+func (standardHeaders) WriteSeekHeaders(h http.Header, r *http.Request, p *SeekPagination) {
+	// Add Link header for pagination info.
+	if link := p.LinkHeader(RequestURL(r)); link != "" {
+		h.Set("Link", link)
+	}
+	h.Set("X-Pagination", p.XPaginationHeader())
 }
@@ -2,7 +2,9 @@ package pg
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -15,15 +17,33 @@ var (
 	//    query := SQL.Update("users").Set("name", "John")....
 	SQL = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-	pool *pgxpool.Pool
+	// ErrPoolExhausted is returned when no connection could be acquired from
+	// the pool within the max acquire wait. See SetMaxAcquireWait.
+	ErrPoolExhausted = errors.New("pg: connection pool exhausted")
+
+	pool           *pgxpool.Pool
+	maxAcquireWait time.Duration
 )
 
 // Init initializes the database connection pool, using the given connection string.
 // See `pgxpool.New` for more details about the format of the connection string.
+//
+// The queries executed through the pool are aggregated into the statistics
+// returned by Stats. The connections are recycled by the policy set by
+// SetRecyclePolicy, if any.
 func Init(ctx context.Context, connString string) (err error) {
-	pool, err = pgxpool.New(ctx, connString)
+	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		return fmt.Errorf("pgxpool.New failed: %w", err)
+		return fmt.Errorf("pgxpool.ParseConfig failed: %w", err)
+	}
+	config.ConnConfig.Tracer = statsTracer{}
+	if recycling.enabled {
+		applyRecyclePolicy(config)
+	}
+
+	pool, err = pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("pgxpool.NewWithConfig failed: %w", err)
 	}
 	return pool.Ping(context.Background())
 }
@@ -32,3 +52,79 @@ func Init(ctx context.Context, connString string) (err error) {
 func DB() *pgxpool.Pool {
 	return pool
 }
+
+// SetMaxAcquireWait sets the max duration to wait for a connection from the
+// pool. When it elapses, the helpers fail with ErrPoolExhausted instead of
+// waiting until the context is done, so that the callers can distinguish pool
+// saturation from slow queries. Zero (default) means no limit.
+func SetMaxAcquireWait(d time.Duration) {
+	maxAcquireWait = d
+}
+
+// acquire acquires a connection from the pool, with the priority of the
+// context (see WithPriority). It fails fast if the context is already done.
+func acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if priorityFrom(ctx) == Background {
+		done, err := waitBackgroundTurn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+	if maxAcquireWait <= 0 {
+		return DB().Acquire(ctx)
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, maxAcquireWait)
+	defer cancel()
+	conn, err := DB().Acquire(acquireCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(acquireCtx.Err(), context.DeadlineExceeded) {
+		return nil, ErrPoolExhausted
+	}
+	return conn, err
+}
+
+type querierKey struct{}
+
+// WithQuerier returns a copy of ctx carrying q, the helpers (Get, List, Exec,
+// etc.) called with the context run their queries on q instead of the pool,
+// e.g. a single *pgx.Conn, a pgx.Tx, or a mock in the unit tests. The queries
+// still flow through the middlewares registered by Use. WithinTx begins the
+// transaction on q, which requires q to implement
+// Begin(ctx context.Context) (pgx.Tx, error), like *pgx.Conn and pgx.Tx do.
+//
+// Example:
+//
+//	ctx = pg.WithQuerier(ctx, conn)
+//	user, err := pg.Get(ctx, new(User), query)
+func WithQuerier(ctx context.Context, q Querier) context.Context {
+	return context.WithValue(ctx, querierKey{}, q)
+}
+
+func querierFrom(ctx context.Context) Querier {
+	q, _ := ctx.Value(querierKey{}).(Querier)
+	return q
+}
+
+// querier returns the querier to run the queries of the context with: the
+// transaction carried by the context (see WithinTx) if any, or the querier
+// carried by the context (see WithQuerier), otherwise a connection acquired
+// from the pool. The queries flow through the middlewares registered by Use.
+// Call release when done.
+func querier(ctx context.Context) (q Querier, release func(), err error) {
+	if scope := scopeFrom(ctx); scope != nil {
+		return withMiddlewares(scope.tx), func() {}, nil
+	}
+	if q := querierFrom(ctx); q != nil {
+		return withMiddlewares(q), func() {}, nil
+	}
+
+	conn, err := acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return withMiddlewares(conn), conn.Release, nil
+}
@@ -0,0 +1,113 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Priority is the priority class of the operations acquiring connections from
+// the pool, see WithPriority.
+type Priority int
+
+const (
+	// Interactive is the priority of the operations serving the users, the
+	// default.
+	Interactive Priority = iota
+	// Background is the priority of the operations which can wait, e.g. the
+	// exports and the backfills. They acquire connections only when the pool
+	// isn't saturated, see SetReservedConns.
+	Background
+)
+
+// ErrLoadShed is returned to the Background operations which didn't get a
+// connection within the max wait, see SetBackgroundMaxWait.
+var ErrLoadShed = errors.New("pg: background operation shed")
+
+// backgroundPollInterval is the interval to check the pool for the
+// Background operations waiting for a connection.
+const backgroundPollInterval = 10 * time.Millisecond
+
+var (
+	reservedConns     int32
+	backgroundMaxWait time.Duration
+
+	// backgroundTurn serializes the acquisitions of the Background operations,
+	// so that they never take the reserved connections all at once.
+	backgroundTurn = make(chan struct{}, 1)
+)
+
+// SetReservedConns reserves n connections of the pool to the Interactive
+// operations, i.e. the Background operations wait while fewer than n+1
+// connections are idle or can be opened. Zero (default) reserves none, yet
+// the Background operations still wait for a free connection rather than
+// queuing up in the pool ahead of the Interactive ones.
+func SetReservedConns(n int32) {
+	reservedConns = n
+}
+
+// SetBackgroundMaxWait sets the max duration the Background operations wait
+// for a connection. When it elapses, they fail with ErrLoadShed, i.e. the
+// background work is shed while the pool is saturated. Zero (default) means
+// waiting until the context is done.
+func SetBackgroundMaxWait(d time.Duration) {
+	backgroundMaxWait = d
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx running its operations with the
+// priority, i.e. the connections acquired from the pool with the context are
+// acquired with the priority. The transactions and the queriers carried by
+// the context (see WithinTx and WithQuerier) are not affected.
+//
+// Example:
+//
+//	ctx = pg.WithPriority(ctx, pg.Background)
+//	err := export.Next(ctx, id, write)
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFrom(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// waitBackgroundTurn waits until the pool has a free connection besides the
+// reserved ones. Call done once the connection is acquired.
+func waitBackgroundTurn(ctx context.Context) (done func(), err error) {
+	var deadline <-chan time.Time
+	if backgroundMaxWait > 0 {
+		timer := time.NewTimer(backgroundMaxWait)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case backgroundTurn <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-deadline:
+		return nil, ErrLoadShed
+	}
+	done = func() { <-backgroundTurn }
+
+	ticker := time.NewTicker(backgroundPollInterval)
+	defer ticker.Stop()
+	for {
+		stat := DB().Stat()
+		if stat.AcquiredConns()+stat.ConstructingConns() < stat.MaxConns()-reservedConns {
+			return done, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			done()
+			return nil, ctx.Err()
+		case <-deadline:
+			done()
+			return nil, ErrLoadShed
+		}
+	}
+}
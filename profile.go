@@ -0,0 +1,83 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Profile is a named bundle of settings applied to the transactions of the
+// operations opting in, e.g. {"work_mem": "256MB", "statement_timeout": "5min"}
+// for the reports. See RegisterProfile and WithProfile.
+type Profile map[string]string
+
+var profiles = struct {
+	sync.RWMutex
+	m map[string]Profile
+}{m: make(map[string]Profile)}
+
+// RegisterProfile registers the settings of a profile by name, replacing the
+// previous one of the same name.
+//
+// Example:
+//
+//	pg.RegisterProfile("oltp", pg.Profile{"statement_timeout": "2s"})
+//	pg.RegisterProfile("report", pg.Profile{
+//		"work_mem":              "256MB",
+//		"statement_timeout":     "5min",
+//		"cursor_tuple_fraction": "1",
+//	})
+func RegisterProfile(name string, settings Profile) {
+	profiles.Lock()
+	defer profiles.Unlock()
+	profiles.m[name] = settings
+}
+
+type profileKey struct{}
+
+// WithProfile returns a copy of ctx opting in the profile registered by name
+// (see RegisterProfile). The settings of the profile are applied with SET
+// LOCAL to the transactions begun by WithinTx (and RunInTx) with the context,
+// thus they're reset when the transaction ends, and never leak to the other
+// users of the connection. The queries run outside of a transaction are not
+// affected. An unknown profile fails WithinTx.
+//
+// Example:
+//
+//	ctx = pg.WithProfile(ctx, "report")
+//	err := pg.WithinTx(ctx, func(ctx context.Context) error {
+//		_, err := pg.List(ctx, rows, reportQuery)
+//		return err
+//	})
+func WithProfile(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, profileKey{}, name)
+}
+
+// applyProfile applies the settings of the profile of the context, if any,
+// to the transaction.
+func applyProfile(ctx context.Context, q Querier) error {
+	name, ok := ctx.Value(profileKey{}).(string)
+	if !ok {
+		return nil
+	}
+	profiles.RLock()
+	settings, ok := profiles.m[name]
+	profiles.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// The same as SET LOCAL, with the name and the value bound as parameters.
+		if _, err := q.Exec(ctx, "SELECT set_config($1, $2, true)", k, settings[k]); err != nil {
+			return fmt.Errorf("apply setting %s of profile %q: %w", k, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProjectionsSchema is the DDL of the tables storing the checkpoints of the
+// projections and the snapshots of the streams. Run it along with the
+// migrations of the application.
+const ProjectionsSchema = `
+CREATE TABLE IF NOT EXISTS projection_checkpoints (
+	name       text PRIMARY KEY,
+	position   bigint NOT NULL DEFAULT 0,
+	tx_id      bigint NOT NULL DEFAULT 0,
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+ALTER TABLE projection_checkpoints ADD COLUMN IF NOT EXISTS tx_id bigint NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS event_snapshots (
+	stream     text PRIMARY KEY,
+	version    bigint NOT NULL,
+	state      jsonb NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+`
+
+// defaultProjectionBatchSize is the batch size of a projection if unset.
+const defaultProjectionBatchSize = 100
+
+// Projection maintains a read model from the events of the event store. The
+// events are handled in batches, in the order of the transactions appending
+// them (the tx_id column) and then the global order. Each batch is handled in
+// the same transaction as the update of the checkpoint of the projection,
+// thus the read model is maintained idempotently, i.e. an event is applied
+// exactly once even if the projection crashes and restarts.
+//
+// The global positions (ids) are assigned on insertion, thus a transaction
+// committing later may append events of smaller ids. To not skip them, only
+// the events of the transactions older than any one still in progress are
+// handled, i.e. a long-running transaction delays the projections.
+//
+// Example:
+//
+//	projection := &pg.Projection{
+//		Name: "order_summaries",
+//		Handle: func(ctx context.Context, tx pgx.Tx, events []*pg.Event) error {
+//			... // update the read model with tx
+//		},
+//	}
+//	go projection.Run(ctx, time.Second)
+type Projection struct {
+	Name      string
+	BatchSize uint64 // default 100
+
+	// Handle applies a batch of events to the read model, using tx.
+	Handle func(ctx context.Context, tx pgx.Tx, events []*Event) error
+
+	// Reset clears the read model, using tx. Required by Rebuild.
+	Reset func(ctx context.Context, tx pgx.Tx) error
+}
+
+// Step handles the next batch of events. Returns the number of the handled
+// events, zero means the projection has caught up.
+func (p *Projection) Step(ctx context.Context) (int, error) {
+	batchSize := p.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultProjectionBatchSize
+	}
+
+	var count int
+	err := p.inTx(ctx, func(ctx context.Context, tx pgx.Tx, at projectionCheckpoint) (projectionCheckpoint, error) {
+		query := SQL.Select(eventColumns...).Column("tx_id").From("events").
+			Where("(tx_id, id) > (?, ?)", at.TxID, at.Position).
+			// The transactions before the xmin of the snapshot are all done,
+			// no more events of smaller tx_id will be committed.
+			Where("tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint").
+			OrderBy("tx_id", "id").
+			Limit(batchSize)
+		sqlstr, args, err := query.ToSql()
+		if err != nil {
+			return at, fmt.Errorf("assemble query: %w", err)
+		}
+		var rows []*projectedEvent
+		if err := scanAPI(&rows).Select(ctx, withMiddlewares(tx), &rows, sqlstr, args...); err != nil {
+			return at, fmt.Errorf("read events: %w", err)
+		}
+		if len(rows) == 0 {
+			return at, nil
+		}
+		events := make([]*Event, len(rows))
+		for i, row := range rows {
+			events[i] = &row.Event
+		}
+		if err := p.Handle(ctx, tx, events); err != nil {
+			return at, fmt.Errorf("handle events of projection %q: %w", p.Name, err)
+		}
+		count = len(events)
+		last := rows[len(rows)-1]
+		return projectionCheckpoint{TxID: last.TxID, Position: last.ID}, nil
+	})
+	return count, err
+}
+
+// projectedEvent is an event read by a projection, along with the transaction
+// appending it.
+type projectedEvent struct {
+	Event
+	TxID int64
+}
+
+// projectionCheckpoint is the checkpoint of a projection, the last handled
+// event and the transaction appending it.
+type projectionCheckpoint struct {
+	TxID     int64
+	Position int64
+}
+
+// Run handles the events until the context is done. When caught up, it polls
+// the new events at the given interval.
+func (p *Projection) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := p.Step(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Rebuild clears the read model and rewinds the checkpoint, atomically. The
+// read model is rebuilt from the beginning by the following steps.
+func (p *Projection) Rebuild(ctx context.Context) error {
+	if p.Reset == nil {
+		return errors.New("projection has no Reset function")
+	}
+	return p.inTx(ctx, func(ctx context.Context, tx pgx.Tx, _ projectionCheckpoint) (projectionCheckpoint, error) {
+		if err := p.Reset(ctx, tx); err != nil {
+			return projectionCheckpoint{}, fmt.Errorf("reset projection %q: %w", p.Name, err)
+		}
+		return projectionCheckpoint{}, nil
+	})
+}
+
+// Position returns the checkpoint of the projection, i.e. the global position
+// of the last handled event. The events are not handled in the global order
+// (see Projection), thus the events of smaller positions may be yet to be
+// handled.
+func (p *Projection) Position(ctx context.Context) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var position int64
+	err = q.QueryRow(ctx, "SELECT position FROM projection_checkpoints WHERE name = $1", p.Name).Scan(&position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return position, err
+}
+
+// inTx runs fn in a transaction (see RunInTx) holding the lock of the
+// checkpoint, and saves the checkpoint returned by fn as the new one.
+func (p *Projection) inTx(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx, at projectionCheckpoint) (projectionCheckpoint, error)) error {
+	return RunInTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		return p.checkpoint(ctx, tx, fn)
+	})
+}
+
+func (p *Projection) checkpoint(ctx context.Context, tx pgx.Tx, fn func(ctx context.Context, tx pgx.Tx, at projectionCheckpoint) (projectionCheckpoint, error)) error {
+	if _, err := tx.Exec(ctx, "INSERT INTO projection_checkpoints (name) VALUES ($1) ON CONFLICT DO NOTHING", p.Name); err != nil {
+		return fmt.Errorf("create checkpoint of projection %q: %w", p.Name, err)
+	}
+	var at projectionCheckpoint
+	if err := tx.QueryRow(ctx, "SELECT tx_id, position FROM projection_checkpoints WHERE name = $1 FOR UPDATE", p.Name).Scan(&at.TxID, &at.Position); err != nil {
+		return fmt.Errorf("lock checkpoint of projection %q: %w", p.Name, err)
+	}
+
+	next, err := fn(ctx, tx, at)
+	if err != nil {
+		return err
+	}
+	if next != at {
+		if _, err := tx.Exec(ctx, "UPDATE projection_checkpoints SET tx_id = $2, position = $3, updated_at = now() WHERE name = $1", p.Name, next.TxID, next.Position); err != nil {
+			return fmt.Errorf("save checkpoint of projection %q: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot saves the state of the stream at the given version, replacing
+// the previous snapshot. The state is stored as JSON.
+func (s *EventStore) SaveSnapshot(ctx context.Context, stream string, version int64, state any) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	query := SQL.Insert("event_snapshots").
+		Columns("stream", "version", "state").
+		Values(stream, version, raw).
+		Suffix("ON CONFLICT (stream) DO UPDATE SET version = excluded.version, state = excluded.state, created_at = now()")
+	if _, err := Exec(ctx, query); err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadSnapshot decodes the latest snapshot of the stream into state, and
+// returns its version. Returns zero if the stream has no snapshot, the state
+// should then be built from the beginning of the stream. The events after
+// the returned version can be read by ReadStream.
+func (s *EventStore) LoadSnapshot(ctx context.Context, stream string, state any) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var version int64
+	var raw []byte
+	err = q.QueryRow(ctx, "SELECT version, state FROM event_snapshots WHERE stream = $1", stream).Scan(&version, &raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load snapshot: %w", err)
+	}
+	return version, json.Unmarshal(raw, state)
+}
@@ -0,0 +1,137 @@
+package pg
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// ApplyToUpdate applies the filtering ListOptions (e.g. With, Without, the
+// tenant scoping and the soft-delete conditions) to an UPDATE query, so that
+// the bulk updates share the filters of the list endpoints. The options may
+// only add WHERE conditions, the pagination, sorting, joins, etc. are errors.
+//
+// Example:
+//
+//	filters := []pg.ListOption{pg.With("tenant_id", tenantID), pg.With("status", "pending")}
+//	query, err := pg.ApplyToUpdate(pg.SQL.Update("orders").Set("status", "cancelled"), filters...)
+func ApplyToUpdate(ub sq.UpdateBuilder, opts ...ListOption) (sq.UpdateBuilder, error) {
+	conds, err := whereConditions(opts)
+	if err != nil {
+		return ub, err
+	}
+	for _, cond := range conds {
+		ub = ub.Where(cond)
+	}
+	return ub, nil
+}
+
+// ApplyToDelete applies the filtering ListOptions to a DELETE query. See
+// ApplyToUpdate.
+//
+// Example:
+//
+//	query, err := pg.ApplyToDelete(pg.SQL.Delete("orders"), filters...)
+func ApplyToDelete(db sq.DeleteBuilder, opts ...ListOption) (sq.DeleteBuilder, error) {
+	conds, err := whereConditions(opts)
+	if err != nil {
+		return db, err
+	}
+	for _, cond := range conds {
+		db = db.Where(cond)
+	}
+	return db, nil
+}
+
+// whereConditions collects the WHERE conditions the options add to a SELECT
+// query.
+func whereConditions(opts []ListOption) ([]sq.Sqlizer, error) {
+	var conds []sq.Sqlizer
+	for _, opt := range opts {
+		if IsPaginationOption(opt) || IsSortingOption(opt) {
+			return nil, fmt.Errorf("option %T is not a filter", opt)
+		}
+		sb := opt.Apply(sq.SelectBuilder{})
+		for _, part := range []string{"Columns", "From", "Joins", "GroupBys", "HavingParts", "OrderByParts", "Limit", "Offset", "Suffixes"} {
+			if _, ok := builder.Get(sb, part); ok {
+				return nil, fmt.Errorf("option %T is not a filter", opt)
+			}
+		}
+		if parts, ok := builder.Get(sb, "WhereParts"); ok {
+			conds = append(conds, parts.([]sq.Sqlizer)...)
+		}
+	}
+	return conds, nil
+}
+
+// AnyOf returns a ListOption that combines the filtering options into an OR
+// group, e.g. AnyOf(With("status", "active"), With("owner_id", userID)) is
+// (status = 'active' OR owner_id = $1). The no-op options (e.g. With of no
+// values) are left out of the group, and AnyOf of no conditions is a no-op.
+// The options may only add WHERE conditions, see ApplyToUpdate.
+func AnyOf(opts ...ListOption) ListOption {
+	return combineConditions(opts, func(conds []sq.Sqlizer) sq.Sqlizer {
+		return sq.Or(conds)
+	})
+}
+
+// AllOf works like AnyOf, but combines the options into an AND group, e.g.
+// to nest in AnyOf.
+func AllOf(opts ...ListOption) ListOption {
+	return combineConditions(opts, func(conds []sq.Sqlizer) sq.Sqlizer {
+		return sq.And(conds)
+	})
+}
+
+// Not returns a ListOption that negates the conditions of the filtering
+// option, e.g. Not(AnyOf(...)). Not of a no-op option is a no-op.
+func Not(opt ListOption) ListOption {
+	return combineConditions([]ListOption{opt}, func(conds []sq.Sqlizer) sq.Sqlizer {
+		return notCondition{conds[0]}
+	})
+}
+
+func combineConditions(opts []ListOption, combine func(conds []sq.Sqlizer) sq.Sqlizer) ListOption {
+	return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+		var groups []sq.Sqlizer
+		for _, opt := range opts {
+			conds, err := whereConditions([]ListOption{opt})
+			if err != nil {
+				return sb.Where(errCondition{err})
+			}
+			switch len(conds) {
+			case 0: // noop
+			case 1:
+				groups = append(groups, conds[0])
+			default:
+				groups = append(groups, sq.And(conds))
+			}
+		}
+		if len(groups) == 0 {
+			return sb
+		}
+		return sb.Where(combine(groups))
+	})
+}
+
+type notCondition struct {
+	cond sq.Sqlizer
+}
+
+func (c notCondition) ToSql() (string, []any, error) {
+	sqlstr, args, err := c.cond.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return "NOT (" + sqlstr + ")", args, nil
+}
+
+// errCondition fails the assembling of the query with the error.
+type errCondition struct {
+	err error
+}
+
+func (c errCondition) ToSql() (string, []any, error) {
+	return "", nil, c.err
+}
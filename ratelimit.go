@@ -0,0 +1,73 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RateLimitSchema is the DDL of the table storing the token buckets of
+// RateLimit. Run it along with the migrations of the application.
+const RateLimitSchema = `
+CREATE TABLE IF NOT EXISTS rate_limits (
+	key        text PRIMARY KEY,
+	tokens     double precision NOT NULL,
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+`
+
+// rateLimitQuery takes a token from the bucket of the key atomically. The
+// bucket refills limit ($2) tokens per window ($3, in seconds), and holds at
+// most limit tokens. No row is returned when the bucket is empty.
+const rateLimitQuery = `
+INSERT INTO rate_limits AS r (key, tokens, updated_at) VALUES ($1, $2::float8 - 1, now())
+ON CONFLICT (key) DO UPDATE SET
+	tokens = LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated_at) * $2::float8 / $3::float8) - 1,
+	updated_at = now()
+WHERE LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated_at) * $2::float8 / $3::float8) >= 1
+RETURNING tokens
+`
+
+// RateLimitResult is the result of RateLimit.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int64 // tokens left in the bucket after this call
+}
+
+// RateLimit implements a token bucket rate limiter in the rate_limits table
+// (see RateLimitSchema). It allows up to limit calls per window for the key,
+// refilling the tokens continuously, e.g. at most 100 calls per minute.
+//
+// Usage: rate limiting for small deployments without Redis.
+//
+// Example:
+//
+//	result, err := pg.RateLimit(ctx, "login:"+ip, 5, time.Minute)
+//	if err == nil && !result.Allowed {
+//		http.Error(rw, "too many requests", http.StatusTooManyRequests)
+//	}
+func RateLimit(ctx context.Context, key string, limit int64, window time.Duration) (*RateLimitResult, error) {
+	if limit <= 0 || window <= 0 {
+		return nil, errors.New("limit and window must be positive")
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var tokens float64
+	err = q.QueryRow(ctx, rateLimitQuery, key, float64(limit), window.Seconds()).Scan(&tokens)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &RateLimitResult{Allowed: false}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("take token: %w", err)
+	}
+	return &RateLimitResult{Allowed: true, Remaining: int64(math.Floor(tokens))}, nil
+}
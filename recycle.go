@@ -0,0 +1,169 @@
+package pg
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	defaultRecycleInterval = 30 * time.Second
+	recycleProbeTimeout    = 5 * time.Second
+)
+
+// RecycleReason is the reason a connection is recycled, see RecyclePolicy.
+type RecycleReason string
+
+const (
+	RecycleIdle    RecycleReason = "idle"    // idle beyond MaxIdle
+	RecycleDead    RecycleReason = "dead"    // failed the probe
+	RecycleDemoted RecycleReason = "demoted" // to a server in recovery, with RequirePrimary
+)
+
+// RecycleEvent is a connection recycled by the recycler, see RunRecycler.
+type RecycleEvent struct {
+	PID    uint32 // the backend process ID of the connection
+	Reason RecycleReason
+	Idle   time.Duration
+	Err    error // the error of the probe, if any
+}
+
+// RecyclePolicy configures how the connections of the pool are recycled, so
+// that the dead ones, e.g. after a database restart or a failover, are closed
+// before the queries run into them rather than after.
+type RecyclePolicy struct {
+	MaxLifetime    time.Duration // the max lifetime of the connections, see pgxpool.Config.MaxConnLifetime
+	LifetimeJitter time.Duration // the random jitter added to MaxLifetime, so that the connections aren't recycled all at once
+	MaxIdle        time.Duration // the idle connections beyond are recycled by the recycler, zero for never
+	Interval       time.Duration // the interval of the probes of the recycler, 30s by default
+	RequirePrimary bool          // recycle the connections to a server in recovery, i.e. a demoted primary
+	OnRecycle      func(event RecycleEvent)
+}
+
+var recycling struct {
+	enabled   bool
+	policy    RecyclePolicy
+	idleSince sync.Map // *pgx.Conn -> time.Time
+	probing   sync.Map // *pgx.Conn -> struct{}
+}
+
+// SetRecyclePolicy sets the policy recycling the connections of the pool.
+// Call it before Init, and run the recycler with RunRecycler.
+//
+// Example:
+//
+//	pg.SetRecyclePolicy(pg.RecyclePolicy{
+//		MaxLifetime:    30 * time.Minute,
+//		LifetimeJitter: 5 * time.Minute,
+//		MaxIdle:        5 * time.Minute,
+//		RequirePrimary: true,
+//		OnRecycle: func(e pg.RecycleEvent) {
+//			log.Printf("recycled connection %d (%s): %v", e.PID, e.Reason, e.Err)
+//		},
+//	})
+//	err := pg.Init(ctx, connString)
+//	go pg.RunRecycler(ctx)
+func SetRecyclePolicy(policy RecyclePolicy) {
+	recycling.enabled = true
+	recycling.policy = policy
+}
+
+// applyRecyclePolicy configures the pool with the policy, and tracks the
+// idle time of the connections.
+func applyRecyclePolicy(config *pgxpool.Config) {
+	policy := recycling.policy
+	if policy.MaxLifetime > 0 {
+		config.MaxConnLifetime = policy.MaxLifetime
+	}
+	if policy.LifetimeJitter > 0 {
+		config.MaxConnLifetimeJitter = policy.LifetimeJitter
+	}
+	config.AfterRelease = func(conn *pgx.Conn) bool {
+		// A probe doesn't reset the idle time.
+		if _, ok := recycling.probing.LoadAndDelete(conn); !ok {
+			recycling.idleSince.Store(conn, time.Now())
+		}
+		return true
+	}
+	config.BeforeClose = func(conn *pgx.Conn) {
+		recycling.idleSince.Delete(conn)
+		recycling.probing.Delete(conn)
+	}
+}
+
+// RunRecycler probes the idle connections of the pool every Interval of the
+// policy (see SetRecyclePolicy) until the context is done, recycling the ones
+// idle beyond MaxIdle, failing a ping, or to a server in recovery if
+// RequirePrimary.
+func RunRecycler(ctx context.Context) error {
+	interval := recycling.policy.Interval
+	if interval <= 0 {
+		interval = defaultRecycleInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		recycleIdleConns(ctx)
+	}
+}
+
+func recycleIdleConns(ctx context.Context) {
+	policy := recycling.policy
+	for _, c := range DB().AcquireAllIdle(ctx) {
+		conn := c.Conn()
+		now := time.Now()
+		since, ok := recycling.idleSince.LoadOrStore(conn, now)
+		if !ok {
+			since = now
+		}
+		event := RecycleEvent{PID: conn.PgConn().PID(), Idle: now.Sub(since.(time.Time))}
+
+		if policy.MaxIdle > 0 && event.Idle > policy.MaxIdle {
+			event.Reason = RecycleIdle
+		} else {
+			event.Reason, event.Err = probeConn(ctx, conn, policy.RequirePrimary)
+		}
+		if event.Reason == "" || ctx.Err() != nil {
+			recycling.probing.Store(conn, struct{}{})
+			c.Release()
+			continue
+		}
+
+		recycling.idleSince.Delete(conn)
+		closeCtx, cancel := context.WithTimeout(context.Background(), recycleProbeTimeout)
+		c.Hijack().Close(closeCtx)
+		cancel()
+		if policy.OnRecycle != nil {
+			policy.OnRecycle(event)
+		}
+	}
+}
+
+// probeConn probes the connection, and returns the reason to recycle it, if
+// any.
+func probeConn(ctx context.Context, conn *pgx.Conn, requirePrimary bool) (RecycleReason, error) {
+	ctx, cancel := context.WithTimeout(ctx, recycleProbeTimeout)
+	defer cancel()
+	if !requirePrimary {
+		if err := conn.Ping(ctx); err != nil {
+			return RecycleDead, err
+		}
+		return "", nil
+	}
+	var inRecovery bool
+	if err := conn.QueryRow(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return RecycleDead, err
+	}
+	if inRecovery {
+		return RecycleDemoted, nil
+	}
+	return "", nil
+}
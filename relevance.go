@@ -0,0 +1,150 @@
+package pg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Weighted is the weights of the columns searched by WithRelevance, from 'A'
+// (the highest) to 'D', e.g. Weighted{"title": 'A', "body": 'B'}.
+type Weighted map[string]byte
+
+// RelevanceOption configures a WithRelevance.
+type RelevanceOption func(*relevanceSettings)
+
+type relevanceSettings struct {
+	config         string
+	headlineColumn string
+	headlineAlias  string
+}
+
+// RelevanceConfig sets the text search configuration of a WithRelevance,
+// e.g. "english". The default one of the database applies otherwise.
+func RelevanceConfig(config string) RelevanceOption {
+	return func(s *relevanceSettings) {
+		s.config = config
+	}
+}
+
+// RelevanceHeadline selects the snippet of the column with the matched words
+// highlighted (ts_headline) as the alias, e.g. the column of the headline
+// field of the struct scanned into.
+func RelevanceHeadline(column, as string) RelevanceOption {
+	return func(s *relevanceSettings) {
+		s.headlineColumn = column
+		s.headlineAlias = as
+	}
+}
+
+type withRelevanceOption struct {
+	opts []ListOption
+}
+
+func (o *withRelevanceOption) Apply(sb sq.SelectBuilder) sq.SelectBuilder {
+	for _, opt := range o.opts {
+		sb = opt.Apply(sb)
+	}
+	return sb
+}
+
+func (o *withRelevanceOption) listOptions() []ListOption {
+	return o.opts
+}
+
+// WithRelevance returns a ListOption that searches the term in the columns
+// weighted by their importance, i.e. filters the rows whose weighted tsvector
+// of the columns matches plainto_tsquery($1), and sorts them by ts_rank_cd,
+// the most relevant first, e.g. a match in the title ranks higher than one in
+// the body. The tsvector is computed on the fly, see WithFullTextSearch for a
+// stored (and indexed) one.
+//
+// When the given term is empty, the returned ListOption is a no-op.
+//
+// Example:
+//
+//	type Result struct {
+//		ID       int64
+//		Title    string
+//		Headline string
+//	}
+//
+//	pagination, err := pg.List(ctx, results, pg.SQL.Select("id", "title").From("articles"),
+//		pg.WithRelevance(q, pg.Weighted{"title": 'A', "body": 'B'},
+//			pg.RelevanceConfig("english"),
+//			pg.RelevanceHeadline("body", "headline"),
+//		),
+//	)
+func WithRelevance(term string, weights Weighted, opts ...RelevanceOption) ListOption {
+	if term == "" {
+		return &withRelevanceOption{}
+	}
+	settings := new(relevanceSettings)
+	for _, opt := range opts {
+		opt(settings)
+	}
+	var config []string
+	if settings.config != "" {
+		config = []string{settings.config}
+	}
+	tsquery, queryArgs := tsQuery("plainto_tsquery", term, config)
+
+	columns := make([]string, 0, len(weights))
+	for column := range weights {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	var vectors []string
+	var vectorArgs []any
+	for _, column := range columns {
+		weight := weights[column]
+		if weight < 'A' || weight > 'D' {
+			err := fmt.Errorf("invalid weight %q of %s, expect A, B, C or D", weight, column)
+			return ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+				return sb.Where(errCondition{err})
+			})
+		}
+		vector, args := toTSVector(column, config)
+		vectors = append(vectors, fmt.Sprintf("setweight(%s, '%c')", vector, weight))
+		vectorArgs = append(vectorArgs, args...)
+	}
+	if len(vectors) == 0 {
+		return &withRelevanceOption{}
+	}
+	vector := "(" + strings.Join(vectors, " || ") + ")"
+	args := append(append([]any{}, vectorArgs...), queryArgs...)
+
+	relevance := &withRelevanceOption{opts: []ListOption{
+		ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+			return sb.Where(vector+" @@ "+tsquery, args...)
+		}),
+		&withSortByOption{
+			columnName: fmt.Sprintf("ts_rank_cd(%s, %s)", vector, tsquery),
+			direction:  "desc",
+			args:       args,
+		},
+	}}
+	if settings.headlineColumn != "" {
+		document := "coalesce(" + settings.headlineColumn + ", '')"
+		headlineArgs := queryArgs
+		if len(config) > 0 {
+			document = "?::regconfig, " + document
+			headlineArgs = append([]any{config[0]}, queryArgs...)
+		}
+		headline := sq.Expr(fmt.Sprintf("ts_headline(%s, %s) AS %s", document, tsquery, QuoteIdent(settings.headlineAlias)), headlineArgs...)
+		relevance.opts = append(relevance.opts, ListOptionFunc(func(sb sq.SelectBuilder) sq.SelectBuilder {
+			return sb.Column(headline)
+		}))
+	}
+	return relevance
+}
+
+// toTSVector returns the tsvector expression of the column, NULL as empty.
+func toTSVector(column string, config []string) (string, []any) {
+	if len(config) > 0 && config[0] != "" {
+		return "to_tsvector(?::regconfig, coalesce(" + column + ", ''))", []any{config[0]}
+	}
+	return "to_tsvector(coalesce(" + column + ", ''))", nil
+}
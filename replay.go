@@ -0,0 +1,325 @@
+package pg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RecordedQuery is a query captured by the Record middleware. It's written
+// as one JSON line.
+type RecordedQuery struct {
+	SQL       string        `json:"sql"`
+	Args      []any         `json:"args"`
+	ArgTypes  []string      `json:"arg_types,omitempty"` // Go types of the args, see Record
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Record returns a QueryMiddleware that captures a ratio (0.0~1.0) of the
+// queries as (SQL, args, timing) tuples and writes them to w as JSON lines.
+// The recorded queries can be re-run by Replay.
+//
+// The Go types of the args are recorded along, thus the args of the basic
+// types, []byte, time.Time, time.Duration, json.RawMessage and the slices of
+// strings and numbers are replayed as the same types, e.g. a []byte isn't
+// replayed as its base64 string. The args of the other types are replayed as
+// their JSON values.
+//
+// Example:
+//
+//	f, err := os.Create("queries.jsonl")
+//	pg.Use(pg.Record(f, 0.01))
+func Record(w io.Writer, ratio float64) QueryMiddleware {
+	var mu sync.Mutex
+	write := func(q RecordedQuery) {
+		line, err := json.Marshal(q)
+		if err != nil {
+			return // args not serializable, skip
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(line, '\n'))
+	}
+	record := func(sql string, args []any, start time.Time, err error) {
+		q := RecordedQuery{SQL: sql, Args: args, ArgTypes: argTypes(args), StartedAt: start, Duration: time.Since(start)}
+		if err != nil {
+			q.Error = err.Error()
+		}
+		write(q)
+	}
+
+	return func(next Querier) Querier {
+		return &QuerierFuncs{
+			Next: next,
+			ExecFunc: func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+				if rand.Float64() >= ratio {
+					return next.Exec(ctx, sql, args...)
+				}
+				start := time.Now()
+				tag, err := next.Exec(ctx, sql, args...)
+				record(sql, args, start, err)
+				return tag, err
+			},
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				if rand.Float64() >= ratio {
+					return next.Query(ctx, sql, args...)
+				}
+				start := time.Now()
+				rows, err := next.Query(ctx, sql, args...)
+				if err != nil {
+					record(sql, args, start, err)
+					return rows, err
+				}
+				return &recordRows{Rows: rows, onDone: func(err error) {
+					record(sql, args, start, err)
+				}}, nil
+			},
+			QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+				if rand.Float64() >= ratio {
+					return next.QueryRow(ctx, sql, args...)
+				}
+				start := time.Now()
+				return &recordRow{Row: next.QueryRow(ctx, sql, args...), onDone: func(err error) {
+					record(sql, args, start, err)
+				}}
+			},
+		}
+	}
+}
+
+// recordRows calls onDone once the rows are consumed or closed, so that the
+// timing covers reading all the rows.
+type recordRows struct {
+	pgx.Rows
+	done   bool
+	onDone func(error)
+}
+
+func (r *recordRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.finish()
+	return false
+}
+
+func (r *recordRows) Close() {
+	r.Rows.Close()
+	r.finish()
+}
+
+func (r *recordRows) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+	r.onDone(r.Rows.Err())
+}
+
+type recordRow struct {
+	pgx.Row
+	onDone func(error)
+}
+
+func (r *recordRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	r.onDone(err)
+	return err
+}
+
+// ReplayResult reports the outcome of Replay.
+type ReplayResult struct {
+	Queries  int64
+	Errors   int64
+	Duration time.Duration
+}
+
+// Replay re-runs the queries recorded by Record from the given file against
+// the database, e.g. a staging database, with the given concurrency. Failed
+// queries are counted but don't stop the replay. The replayed queries don't
+// flow through the middlewares registered by Use.
+//
+// Usage: load testing and query plan testing.
+func Replay(ctx context.Context, filename string, concurrency int) (*ReplayResult, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		result  = new(ReplayResult)
+		queries = make(chan RecordedQuery)
+		wg      sync.WaitGroup
+		start   = time.Now()
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range queries {
+				atomic.AddInt64(&result.Queries, 1)
+				if err := replayQuery(ctx, q); err != nil {
+					atomic.AddInt64(&result.Errors, 1)
+				}
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() && ctx.Err() == nil {
+		q, err := decodeRecordedQuery(scanner.Bytes())
+		if err != nil {
+			close(queries)
+			wg.Wait()
+			return nil, fmt.Errorf("decode recorded query: %w", err)
+		}
+		queries <- q
+	}
+	close(queries)
+	wg.Wait()
+	result.Duration = time.Since(start)
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	return result, ctx.Err()
+}
+
+func replayQuery(ctx context.Context, q RecordedQuery) error {
+	conn, err := acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return err
+	}
+	rows.Close() // discard the rows
+	return rows.Err()
+}
+
+// argTypes returns the Go types of the args, restored by decodeRecordedArg.
+func argTypes(args []any) []string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case json.RawMessage, *json.RawMessage:
+			// Named explicitly, %T varies with the Go versions.
+			types[i] = "json.RawMessage"
+		default:
+			types[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return types
+}
+
+// decodeRecordedQuery decodes a line written by Record, restoring the args as
+// their recorded types (see decodeRecordedArg).
+func decodeRecordedQuery(line []byte) (RecordedQuery, error) {
+	var raw struct {
+		RecordedQuery
+		Args []json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return raw.RecordedQuery, err
+	}
+	q := raw.RecordedQuery
+	q.Args = make([]any, len(raw.Args))
+	for i, arg := range raw.Args {
+		var typ string
+		if i < len(q.ArgTypes) {
+			typ = q.ArgTypes[i]
+		}
+		v, err := decodeRecordedArg(arg, typ)
+		if err != nil {
+			return q, fmt.Errorf("decode arg $%d of type %s: %w", i+1, typ, err)
+		}
+		q.Args[i] = v
+	}
+	return q, nil
+}
+
+// decodeRecordedArg decodes an arg as its recorded Go type, the pointers as
+// their elements. The integers are restored as int64 (uint64 if unsigned).
+// The args of the other types, or recorded without types, are decoded as JSON
+// values, whose numbers are restored as int64 or float64.
+func decodeRecordedArg(data json.RawMessage, typ string) (any, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	var dest any
+	switch strings.TrimLeft(typ, "*") {
+	case "string":
+		dest = new(string)
+	case "bool":
+		dest = new(bool)
+	case "int", "int8", "int16", "int32", "int64":
+		dest = new(int64)
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		dest = new(uint64)
+	case "float32", "float64":
+		dest = new(float64)
+	case "[]uint8":
+		dest = new([]byte)
+	case "time.Time":
+		dest = new(time.Time)
+	case "time.Duration":
+		dest = new(time.Duration)
+	case "json.RawMessage":
+		return append(json.RawMessage(nil), data...), nil
+	case "[]string":
+		dest = new([]string)
+	case "[]int", "[]int16", "[]int32", "[]int64":
+		dest = new([]int64)
+	case "[]float32", "[]float64":
+		dest = new([]float64)
+	default:
+		return decodeJSONValue(data)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(dest).Elem().Interface(), nil
+}
+
+// decodeJSONValue decodes a JSON value, restoring the numbers as int64 or
+// float64.
+func decodeJSONValue(data json.RawMessage) (any, error) {
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return v, nil
+}
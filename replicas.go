@@ -0,0 +1,154 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReplicaMaxLag        = 10 * time.Second
+	defaultReplicaCheckInterval = 5 * time.Second
+)
+
+// ReplicaStatus is the health of a replica in a ReplicaSet.
+type ReplicaStatus struct {
+	Replica   Querier       `json:"-"`
+	Lag       time.Duration `json:"lag"`
+	Evicted   bool          `json:"evicted"`
+	Err       error         `json:"-"` // the error of the last check, if any
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// ReplicaSet spreads the reads across the replicas in rotation, and tracks
+// their replay lag, i.e. how far the replayed WAL (pg_last_wal_replay_lsn) is
+// behind, in time. A replica lagging more than MaxLag, or failing the check,
+// is removed from the rotation until it catches up.
+//
+// Example:
+//
+//	replicas := pg.NewReplicaSet(replica1, replica2)
+//	replicas.OnEvict = func(s pg.ReplicaStatus) { log.Printf("replica evicted, lag %s: %v", s.Lag, s.Err) }
+//	go replicas.Run(ctx)
+//
+//	ctx = replicas.WithReplica(ctx) // the reads of ctx are served by a replica
+type ReplicaSet struct {
+	MaxLag    time.Duration // 10s by default
+	Interval  time.Duration // the interval of the checks of Run, 5s by default
+	OnEvict   func(status ReplicaStatus)
+	OnRestore func(status ReplicaStatus)
+
+	mu       sync.Mutex
+	statuses []ReplicaStatus
+	next     int
+}
+
+// NewReplicaSet creates a ReplicaSet of the replicas, all in the rotation
+// until checked.
+func NewReplicaSet(replicas ...Querier) *ReplicaSet {
+	s := &ReplicaSet{statuses: make([]ReplicaStatus, len(replicas))}
+	for i, replica := range replicas {
+		s.statuses[i].Replica = replica
+	}
+	return s
+}
+
+// Run checks the replicas every Interval until the context is done.
+func (s *ReplicaSet) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks the replay lag of the replicas once, evicting the lagging ones
+// and restoring the ones caught up.
+func (s *ReplicaSet) Check(ctx context.Context) {
+	maxLag := s.MaxLag
+	if maxLag <= 0 {
+		maxLag = defaultReplicaMaxLag
+	}
+
+	s.mu.Lock()
+	replicas := make([]Querier, len(s.statuses))
+	for i, status := range s.statuses {
+		replicas[i] = status.Replica
+	}
+	s.mu.Unlock()
+
+	for i, replica := range replicas {
+		lag, err := replicaLag(ctx, replica)
+		evicted := err != nil || lag > maxLag
+
+		s.mu.Lock()
+		wasEvicted := s.statuses[i].Evicted
+		s.statuses[i] = ReplicaStatus{Replica: replica, Lag: lag, Evicted: evicted, Err: err, CheckedAt: time.Now()}
+		status := s.statuses[i]
+		s.mu.Unlock()
+
+		switch {
+		case evicted && !wasEvicted && s.OnEvict != nil:
+			s.OnEvict(status)
+		case !evicted && wasEvicted && s.OnRestore != nil:
+			s.OnRestore(status)
+		}
+	}
+}
+
+// Statuses returns a snapshot of the health of the replicas, e.g. to be
+// exported as metrics.
+func (s *ReplicaSet) Statuses() []ReplicaStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReplicaStatus(nil), s.statuses...)
+}
+
+// Pick returns the next replica in the rotation, or nil if all the replicas
+// are evicted.
+func (s *ReplicaSet) Pick() Querier {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range s.statuses {
+		status := s.statuses[s.next]
+		s.next = (s.next + 1) % len(s.statuses)
+		if !status.Evicted {
+			return status.Replica
+		}
+	}
+	return nil
+}
+
+// WithReplica returns a context whose queries are served by the next replica
+// in the rotation (see WithQuerier). If all the replicas are evicted, the
+// context is returned untouched, i.e. the reads fall back to the primary.
+func (s *ReplicaSet) WithReplica(ctx context.Context) context.Context {
+	if replica := s.Pick(); replica != nil {
+		return WithQuerier(ctx, replica)
+	}
+	return ctx
+}
+
+// replicaLag returns the time since the last replayed transaction, or zero if
+// the replica has replayed all the WAL received, or isn't a replica at all.
+func replicaLag(ctx context.Context, replica Querier) (time.Duration, error) {
+	var seconds float64
+	err := replica.QueryRow(ctx, `SELECT COALESCE(CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+	END, 0)::float8`).Scan(&seconds)
+	if err != nil {
+		return 0, fmt.Errorf("check replay lag: %w", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
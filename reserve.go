@@ -0,0 +1,45 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ErrSlotTaken is returned by TryReserve when the reservation overlaps an
+// existing one.
+var ErrSlotTaken = errors.New("pg: slot already taken")
+
+// TryReserve inserts the reservation as a row of the table guarded by an
+// exclusion constraint, e.g. EXCLUDE USING gist (room_id WITH =, during WITH
+// &&), and returns ErrSlotTaken if it overlaps an existing one, i.e. the
+// constraint is violated. Like any failed statement, the violation aborts the
+// transaction of the context if any (see WithinTx).
+//
+// Example:
+//
+//	err := pg.TryReserve(ctx, "bookings", sq.Eq{
+//		"room_id": roomID,
+//		"during":  pgtype.Range[time.Time]{Lower: from, Upper: to, LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Valid: true},
+//	})
+//	if errors.Is(err, pg.ErrSlotTaken) {
+//		// offer another slot
+//	}
+func TryReserve(ctx context.Context, table string, reservation sq.Eq) error {
+	columns := sortedKeys(reservation)
+	values := make([]any, len(columns))
+	for i, col := range columns {
+		values[i] = reservation[col]
+	}
+
+	_, err := Exec(ctx, SQL.Insert(QuoteIdent(table)).Columns(quoteIdents(columns)...).Values(values...))
+	if IsExclusionViolation(err) {
+		return ErrSlotTaken
+	}
+	if err != nil {
+		return fmt.Errorf("reserve in %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultRetentionBatchSize = 5000
+	defaultRetentionPause     = 100 * time.Millisecond
+)
+
+// RetentionStrategy is the way a retention policy purges the expired rows.
+type RetentionStrategy int
+
+const (
+	// RetentionDelete deletes the expired rows in batches, see
+	// DeleteInBatches.
+	RetentionDelete RetentionStrategy = iota
+	// RetentionDropPartitions drops the partitions of a table partitioned by
+	// range on the column whose upper bounds are not after the cutoff, i.e.
+	// all their rows are expired. The rows of the other partitions are kept,
+	// even when expired.
+	RetentionDropPartitions
+)
+
+// RetentionStats is the metrics of a retention policy.
+type RetentionStats struct {
+	Table      string    `json:"table"`
+	Runs       int64     `json:"runs"`
+	Purged     int64     `json:"purged"`      // the rows purged by all the runs
+	LastPurged int64     `json:"last_purged"` // the rows purged by the last run
+	LastRunAt  time.Time `json:"last_run_at"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// ArchiveFunc opens the writer archiving a batch (numbered from 0) of the
+// expired rows of the table, e.g. an upload to S3 through a gzip writer. The
+// rows are written in CSV with a header. The batch is deleted only if all the
+// rows are written and the writer closes successfully.
+type ArchiveFunc func(ctx context.Context, table string, batch int) (io.WriteCloser, error)
+
+type retentionPolicy struct {
+	table    string
+	column   string
+	keepFor  time.Duration
+	strategy RetentionStrategy
+	archive  ArchiveFunc
+	stats    RetentionStats
+}
+
+// RetentionEngine enforces the data retention policies of the tables, i.e.
+// purges the rows older than the retention periods. See Retention.
+type RetentionEngine struct {
+	BatchSize uint64        // the batch size of RetentionDelete, 5000 by default
+	Pause     time.Duration // the pause between the batches of RetentionDelete, 100ms by default
+
+	mu       sync.Mutex
+	policies map[string]*retentionPolicy
+}
+
+// Retention is the retention engine of the package.
+//
+// Example:
+//
+//	pg.Retention.Register("sessions", "expires_at", 0, pg.RetentionDelete)
+//	pg.Retention.Register("events", "created_at", 90*24*time.Hour, pg.RetentionDropPartitions)
+//	pg.Retention.Schedule("@hourly")
+//	go pg.RunScheduler(ctx)
+var Retention = &RetentionEngine{}
+
+// Register registers the policy purging the rows of the table whose column
+// (a timestamp) is older than keepFor, replacing the previous policy of the
+// table.
+func (e *RetentionEngine) Register(table, olderThanColumn string, keepFor time.Duration, strategy RetentionStrategy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.policies == nil {
+		e.policies = make(map[string]*retentionPolicy)
+	}
+	e.policies[table] = &retentionPolicy{
+		table:    table,
+		column:   olderThanColumn,
+		keepFor:  keepFor,
+		strategy: strategy,
+		stats:    RetentionStats{Table: table},
+	}
+}
+
+// Archive sets the archive step of the policy of the table: the expired rows
+// are streamed with COPY to the writers opened by open before they're
+// deleted. With RetentionDelete, each batch is archived and deleted at once
+// by COPY (DELETE ... RETURNING *), in a transaction committed after the
+// writer closes, with RetentionDropPartitions each partition is archived as
+// a batch before it's dropped. Thus no rows are deleted without being
+// archived, while a batch may be archived twice, if the commit fails after
+// the writer closes.
+//
+// Example:
+//
+//	pg.Retention.Archive("events", func(ctx context.Context, table string, batch int) (io.WriteCloser, error) {
+//		return uploader.Create(fmt.Sprintf("%s/%s-%d.csv.gz", table, time.Now().Format("20060102"), batch))
+//	})
+func (e *RetentionEngine) Archive(table string, open ArchiveFunc) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	policy, ok := e.policies[table]
+	if !ok {
+		return fmt.Errorf("no retention policy of %s", table)
+	}
+	policy.archive = open
+	return nil
+}
+
+// Schedule schedules the enforcement of the policies on the cron expression
+// as the task "pg-retention", see Schedule and RunScheduler.
+func (e *RetentionEngine) Schedule(cron string, opts ...ScheduleOption) error {
+	return Schedule("pg-retention", cron, func(ctx context.Context, tick time.Time) error {
+		return e.Enforce(ctx)
+	}, opts...)
+}
+
+// Enforce enforces all the policies once, in the order of the tables. The
+// failures of the policies don't stop the others, they're returned joined.
+func (e *RetentionEngine) Enforce(ctx context.Context) error {
+	e.mu.Lock()
+	policies := make([]*retentionPolicy, 0, len(e.policies))
+	for _, policy := range e.policies {
+		policies = append(policies, policy)
+	}
+	e.mu.Unlock()
+	sort.Slice(policies, func(i, j int) bool { return policies[i].table < policies[j].table })
+
+	var errs []error
+	for _, policy := range policies {
+		purged, err := e.enforce(ctx, policy)
+
+		e.mu.Lock()
+		policy.stats.Runs++
+		policy.stats.Purged += purged
+		policy.stats.LastPurged = purged
+		policy.stats.LastRunAt = time.Now()
+		policy.stats.LastError = ""
+		if err != nil {
+			policy.stats.LastError = err.Error()
+		}
+		e.mu.Unlock()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("enforce retention of %s: %w", policy.table, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns the metrics of the policies, in the order of the tables.
+func (e *RetentionEngine) Stats() []RetentionStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	stats := make([]RetentionStats, 0, len(e.policies))
+	for _, policy := range e.policies {
+		stats = append(stats, policy.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Table < stats[j].Table })
+	return stats
+}
+
+func (e *RetentionEngine) enforce(ctx context.Context, policy *retentionPolicy) (int64, error) {
+	cutoff := time.Now().Add(-policy.keepFor)
+	switch policy.strategy {
+	case RetentionDelete:
+		batchSize, pause := e.BatchSize, e.Pause
+		if batchSize == 0 {
+			batchSize = defaultRetentionBatchSize
+		}
+		if pause <= 0 {
+			pause = defaultRetentionPause
+		}
+		if policy.archive != nil {
+			return archiveInBatches(ctx, policy, cutoff, batchSize, pause)
+		}
+		query := SQL.Delete(QuoteIdent(policy.table)).Where(sq.Lt{policy.column: cutoff})
+		return DeleteInBatches(ctx, query, batchSize, pause, nil)
+	case RetentionDropPartitions:
+		return dropExpiredPartitions(ctx, policy, cutoff)
+	default:
+		return 0, fmt.Errorf("unknown retention strategy %d", policy.strategy)
+	}
+}
+
+// dropExpiredPartitions drops the range partitions of the table whose upper
+// bounds are not after the cutoff, and returns the estimated number of their
+// rows.
+func dropExpiredPartitions(ctx context.Context, policy *retentionPolicy, cutoff time.Time) (int64, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, `SELECT c.oid::regclass::text, GREATEST(c.reltuples, 0)::bigint
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass
+		AND substring(pg_get_expr(c.relpartbound, c.oid) from 'TO \(''([^'']+)''\)')::timestamptz <= $2`,
+		QuoteIdent(policy.table), cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("list expired partitions: %w", err)
+	}
+	type partition struct {
+		name string
+		rows int64 // estimated
+	}
+	var partitions []partition
+	var p partition
+	if _, err := pgx.ForEachRow(rows, []any{&p.name, &p.rows}, func() error {
+		partitions = append(partitions, p)
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("list expired partitions: %w", err)
+	}
+
+	var purged int64
+	for i, p := range partitions {
+		// The name is quoted by regclass.
+		n := p.rows
+		if policy.archive == nil {
+			_, err = q.Exec(ctx, "DROP TABLE "+p.name)
+		} else {
+			n, err = archiveBatch(ctx, policy, i, "SELECT * FROM "+p.name, "DROP TABLE "+p.name)
+		}
+		if err != nil {
+			return purged, fmt.Errorf("drop partition %s: %w", p.name, err)
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+// archiveInBatches archives and deletes the expired rows batch by batch.
+func archiveInBatches(ctx context.Context, policy *retentionPolicy, cutoff time.Time, batchSize uint64, pause time.Duration) (int64, error) {
+	table := QuoteIdent(policy.table)
+	// COPY doesn't take bound args, the cutoff is a literal.
+	deleteQuery := fmt.Sprintf(
+		"DELETE FROM %s WHERE (tableoid, ctid) IN (SELECT tableoid, ctid FROM %s WHERE %s < '%s'::timestamptz LIMIT %d) RETURNING *",
+		table, table, QuoteIdent(policy.column), cutoff.UTC().Format(time.RFC3339Nano), batchSize,
+	)
+
+	var purged int64
+	for batch := 0; ; batch++ {
+		// Don't open a writer for no rows.
+		expired, err := hasExpiredRows(ctx, policy, cutoff)
+		if err != nil || !expired {
+			return purged, err
+		}
+
+		n, err := archiveBatch(ctx, policy, batch, deleteQuery, "")
+		purged += n
+		if err != nil {
+			return purged, fmt.Errorf("archive batch %d: %w", batch, err)
+		}
+		if uint64(n) < batchSize {
+			return purged, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return purged, ctx.Err()
+		case <-time.After(pause):
+		}
+	}
+}
+
+// archiveBatch copies the rows of the query to the writer of the batch, and
+// runs the statement after, in a transaction committed after the writer
+// closes. Returns the number of the copied rows.
+func archiveBatch(ctx context.Context, policy *retentionPolicy, batch int, query, after string) (int64, error) {
+	if scopeFrom(ctx) != nil {
+		return 0, errors.New("can't archive within a transaction")
+	}
+	tx, release, err := begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer release()
+	defer tx.Rollback(ctx)
+
+	w, err := policy.archive(ctx, policy.table, batch)
+	if err != nil {
+		return 0, fmt.Errorf("open archive: %w", err)
+	}
+	tag, err := tx.Conn().PgConn().CopyTo(ctx, w, "COPY ("+query+") TO STDOUT WITH (FORMAT csv, HEADER)")
+	if err != nil {
+		w.Close()
+		return 0, fmt.Errorf("copy rows: %w", err)
+	}
+	if after != "" {
+		if _, err := tx.Exec(ctx, after); err != nil {
+			w.Close()
+			return 0, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return 0, fmt.Errorf("close archive: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func hasExpiredRows(ctx context.Context, policy *retentionPolicy, cutoff time.Time) (bool, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	var expired bool
+	sqlstr := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s < $1)", QuoteIdent(policy.table), QuoteIdent(policy.column))
+	if err := q.QueryRow(ctx, sqlstr, cutoff).Scan(&expired); err != nil {
+		return false, fmt.Errorf("check expired rows: %w", err)
+	}
+	return expired, nil
+}
@@ -0,0 +1,125 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// SCD2CurrentColumn is the column flagging the current version of a slowly
+// changing dimension (type 2). The validity period of a version is stored in
+// TemporalFromColumn and TemporalToColumn.
+const SCD2CurrentColumn = "is_current"
+
+// UpsertSCD2 maintains a slowly changing dimension (type 2) table: if the
+// attributes of the current version of the entity identified by naturalKey
+// differ from row, the current version is closed (valid_to = now(),
+// is_current = false) and a new current version is inserted, atomically.
+// Returns false if the current version has the same attributes, i.e. nothing
+// changed. If the context carries a transaction (see WithinTx), it runs in it.
+//
+// The calls for the same entity are serialized by a transaction-level advisory
+// lock on the table and the natural key, so that the concurrent calls for a
+// new entity don't both insert a current version. The writes bypassing
+// UpsertSCD2 are not serialized, add a partial unique index to reject the
+// duplicate current versions:
+//
+//	CREATE UNIQUE INDEX ON dim_customers (customer_id) WHERE is_current;
+//
+// Example:
+//
+//	changed, err := pg.UpsertSCD2(ctx, "dim_customers", sq.Eq{"customer_id": 42}, map[string]any{
+//		"name": "John",
+//		"city": "Berlin",
+//	})
+func UpsertSCD2(ctx context.Context, table string, naturalKey sq.Eq, row map[string]any) (changed bool, err error) {
+	if len(naturalKey) == 0 || len(row) == 0 {
+		return false, errors.New("natural key and row must not be empty")
+	}
+	err = WithinTx(ctx, func(ctx context.Context) error {
+		changed, err = upsertSCD2(ctx, table, naturalKey, row)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+func upsertSCD2(ctx context.Context, table string, naturalKey sq.Eq, row map[string]any) (bool, error) {
+	table = QuoteIdent(table)
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	if _, err := q.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", scd2LockKey(table, naturalKey)); err != nil {
+		return false, fmt.Errorf("lock entity: %w", err)
+	}
+
+	// The attributes are compared in SQL, as the types of the columns.
+	changes := make(sq.Or, len(columns))
+	for i, col := range columns {
+		changes[i] = sq.Expr(col+" IS DISTINCT FROM ?", row[col])
+	}
+	current := sq.And{naturalKey, sq.Eq{SCD2CurrentColumn: true}}
+	sqlstr, args, err := SQL.Select().Column(changes).From(table).Where(current).Suffix("FOR UPDATE").ToSql()
+	if err != nil {
+		return false, fmt.Errorf("assemble select query: %w", err)
+	}
+	var changed bool
+	err = q.QueryRow(ctx, sqlstr, args...).Scan(&changed)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// new entity
+	case err != nil:
+		return false, fmt.Errorf("select current version: %w", err)
+	case !changed:
+		return false, nil
+	default:
+		closing := SQL.Update(table).
+			Set(TemporalToColumn, sq.Expr("now()")).
+			Set(SCD2CurrentColumn, false).
+			Where(current)
+		if _, err := execQuery(ctx, q, closing); err != nil {
+			return false, fmt.Errorf("close current version: %w", err)
+		}
+	}
+
+	values := make(map[string]any, len(naturalKey)+len(row)+3)
+	for col, value := range naturalKey {
+		values[col] = value
+	}
+	for col, value := range row {
+		values[col] = value
+	}
+	values[TemporalFromColumn] = sq.Expr("now()")
+	values[TemporalToColumn] = nil
+	values[SCD2CurrentColumn] = true
+	if _, err := execQuery(ctx, q, SQL.Insert(table).SetMap(values)); err != nil {
+		return false, fmt.Errorf("insert current version: %w", err)
+	}
+	return true, nil
+}
+
+// scd2LockKey returns the advisory lock key of the entity, e.g.
+// `dim_customers {"customer_id":42}`.
+func scd2LockKey(table string, naturalKey sq.Eq) string {
+	key, err := json.Marshal(map[string]any(naturalKey)) // sorted by the columns
+	if err != nil {
+		key = []byte(fmt.Sprint(naturalKey))
+	}
+	return table + " " + string(key)
+}
@@ -0,0 +1,264 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// schedulerPollInterval is the interval to check whether the scheduled
+// tasks are due.
+const schedulerPollInterval = 5 * time.Second
+
+// maxCatchUpRuns caps the number of missed runs executed in one go by the
+// CatchUpAll policy.
+const maxCatchUpRuns = 100
+
+// SchedulerSchema is the DDL of the tables storing the schedules and the run
+// history. It's executed (idempotently) by RunScheduler.
+const SchedulerSchema = `
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+	name         text PRIMARY KEY,
+	cron         text NOT NULL,
+	last_tick_at timestamptz,
+	updated_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS scheduled_task_runs (
+	id          bigserial PRIMARY KEY,
+	name        text NOT NULL,
+	tick_at     timestamptz NOT NULL,
+	started_at  timestamptz NOT NULL DEFAULT now(),
+	finished_at timestamptz,
+	error       text
+);
+CREATE INDEX IF NOT EXISTS scheduled_task_runs_name_idx ON scheduled_task_runs (name, tick_at);
+`
+
+// CatchUpPolicy decides what to do with the runs missed while no instance
+// was running the scheduler.
+type CatchUpPolicy int
+
+const (
+	// CatchUpOnce runs the task once for all the missed ticks (default).
+	CatchUpOnce CatchUpPolicy = iota
+	// CatchUpNone skips the missed ticks, only a tick due within the last
+	// minute is run.
+	CatchUpNone
+	// CatchUpAll runs the task for every missed tick, in order.
+	CatchUpAll
+)
+
+// ScheduledFunc is the function of a scheduled task, tick is the scheduled
+// time of the run.
+type ScheduledFunc func(ctx context.Context, tick time.Time) error
+
+// ScheduleOption configures a scheduled task.
+type ScheduleOption func(*scheduledTask)
+
+// WithScheduleErrorHandler sets the function called with the errors of a
+// scheduled task, including the errors returned by the task itself.
+func WithScheduleErrorHandler(onError func(error)) ScheduleOption {
+	return func(t *scheduledTask) {
+		t.onError = onError
+	}
+}
+
+// WithCatchUp sets the catch-up policy of a scheduled task.
+func WithCatchUp(policy CatchUpPolicy) ScheduleOption {
+	return func(t *scheduledTask) {
+		t.catchUp = policy
+	}
+}
+
+type scheduledTask struct {
+	name    string
+	cron    string
+	spec    *cronSpec
+	fn      ScheduledFunc
+	catchUp CatchUpPolicy
+	onError func(error)
+	running atomic.Bool
+}
+
+var (
+	scheduleMu sync.Mutex
+	schedules  = make(map[string]*scheduledTask)
+)
+
+// Schedule registers a task to run on the given cron expression (5 fields,
+// in UTC, e.g. "*/15 * * * *" or "@daily"). The schedules are stored in the
+// database along with the run history, and are run by RunScheduler. An
+// advisory lock guarantees that only one instance runs a task for each tick.
+//
+// Example:
+//
+//	pg.Schedule("purge-sessions", "0 * * * *", func(ctx context.Context, tick time.Time) error {
+//		_, err := pg.Exec(ctx, pg.SQL.Delete("sessions").Where(sq.Lt{"expires_at": tick}))
+//		return err
+//	})
+//	go pg.RunScheduler(ctx)
+func Schedule(name, cron string, fn ScheduledFunc, opts ...ScheduleOption) error {
+	spec, err := parseCron(cron)
+	if err != nil {
+		return err
+	}
+
+	task := &scheduledTask{name: name, cron: cron, spec: spec, fn: fn}
+	for _, opt := range opts {
+		opt(task)
+	}
+
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+	if _, ok := schedules[name]; ok {
+		return fmt.Errorf("task %q is already scheduled", name)
+	}
+	schedules[name] = task
+	return nil
+}
+
+// RunScheduler runs the registered tasks when they are due, until the
+// context is done. It's safe to run the scheduler on multiple instances.
+func RunScheduler(ctx context.Context) error {
+	if _, err := DB().Exec(ctx, SchedulerSchema); err != nil {
+		return fmt.Errorf("create scheduler tables: %w", err)
+	}
+
+	scheduleMu.Lock()
+	tasks := make([]*scheduledTask, 0, len(schedules))
+	for _, task := range schedules {
+		tasks = append(tasks, task)
+	}
+	scheduleMu.Unlock()
+
+	for _, task := range tasks {
+		_, err := DB().Exec(ctx, `INSERT INTO scheduled_tasks (name, cron) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET cron = excluded.cron, updated_at = now()`, task.name, task.cron)
+		if err != nil {
+			return fmt.Errorf("register task %q: %w", task.name, err)
+		}
+	}
+
+	ticker := time.NewTicker(schedulerPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, task := range tasks {
+			if task.running.CompareAndSwap(false, true) {
+				go func(task *scheduledTask) {
+					defer task.running.Store(false)
+					if err := task.tick(ctx); err != nil && task.onError != nil {
+						task.onError(err)
+					}
+				}(task)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs the task for its due ticks, if this instance obtains the lock.
+func (t *scheduledTask) tick(ctx context.Context) error {
+	conn, err := acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", t.name).Scan(&locked); err != nil {
+		return fmt.Errorf("lock task %q: %w", t.name, err)
+	}
+	if !locked {
+		return nil // another instance is running it
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", t.name)
+
+	var lastTick *time.Time
+	err = conn.QueryRow(ctx, "SELECT last_tick_at FROM scheduled_tasks WHERE name = $1", t.name).Scan(&lastTick)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // not registered yet
+	}
+	if err != nil {
+		return fmt.Errorf("load task %q: %w", t.name, err)
+	}
+
+	now := time.Now().UTC()
+	if lastTick == nil {
+		// First time, start from now.
+		_, err := conn.Exec(ctx, "UPDATE scheduled_tasks SET last_tick_at = $2 WHERE name = $1", t.name, now)
+		return err
+	}
+
+	ticks, latest := t.dueTicks(lastTick.UTC(), now)
+	for _, tick := range ticks {
+		runErr := t.run(ctx, conn, tick)
+		if _, err := conn.Exec(ctx, "UPDATE scheduled_tasks SET last_tick_at = $2 WHERE name = $1", t.name, tick); err != nil {
+			return fmt.Errorf("update task %q: %w", t.name, err)
+		}
+		if runErr != nil {
+			return fmt.Errorf("run task %q: %w", t.name, runErr)
+		}
+	}
+	if len(ticks) == 0 && !latest.IsZero() {
+		// Skipped missed ticks.
+		if _, err := conn.Exec(ctx, "UPDATE scheduled_tasks SET last_tick_at = $2 WHERE name = $1", t.name, latest); err != nil {
+			return fmt.Errorf("update task %q: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// dueTicks returns the ticks to run according to the catch-up policy, and
+// the latest due tick.
+func (t *scheduledTask) dueTicks(lastTick, now time.Time) (ticks []time.Time, latest time.Time) {
+	for next := t.spec.next(lastTick); !next.IsZero() && !next.After(now); next = t.spec.next(next) {
+		ticks = append(ticks, next)
+		if t.catchUp == CatchUpAll && len(ticks) == maxCatchUpRuns {
+			break
+		}
+	}
+	if len(ticks) == 0 {
+		return nil, latest
+	}
+
+	latest = ticks[len(ticks)-1]
+	switch t.catchUp {
+	case CatchUpAll:
+		return ticks, latest
+	case CatchUpNone:
+		if now.Sub(latest) > time.Minute {
+			return nil, latest
+		}
+	}
+	return []time.Time{latest}, latest
+}
+
+// run runs the task for a tick and records the run history.
+func (t *scheduledTask) run(ctx context.Context, conn Querier, tick time.Time) error {
+	var runID int64
+	err := conn.QueryRow(ctx, "INSERT INTO scheduled_task_runs (name, tick_at) VALUES ($1, $2) RETURNING id", t.name, tick).Scan(&runID)
+	if err != nil {
+		return fmt.Errorf("record run of task %q: %w", t.name, err)
+	}
+
+	runErr := t.fn(ctx, tick)
+	var errText *string
+	if runErr != nil {
+		s := runErr.Error()
+		errText = &s
+	}
+	if _, err := conn.Exec(ctx, "UPDATE scheduled_task_runs SET finished_at = now(), error = $2 WHERE id = $1", runID, errText); err != nil {
+		return fmt.Errorf("record run of task %q: %w", t.name, err)
+	}
+	return runErr
+}
@@ -0,0 +1,203 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/lann/builder"
+)
+
+// schemaSnapshot maps the tables (both "table" and "schema.table") to their
+// columns.
+type schemaSnapshot map[string]map[string]bool
+
+var schemaCache struct {
+	sync.Mutex
+	snapshot schemaSnapshot
+}
+
+// ReloadSchema reloads the snapshot of the tables and the columns used by
+// ValidateAgainstSchema, e.g. after running the migrations.
+func ReloadSchema(ctx context.Context) error {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	snapshot, err := loadSchema(ctx)
+	if err != nil {
+		return err
+	}
+	schemaCache.snapshot = snapshot
+	return nil
+}
+
+func cachedSchema(ctx context.Context) (schemaSnapshot, error) {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	if schemaCache.snapshot == nil {
+		snapshot, err := loadSchema(ctx)
+		if err != nil {
+			return nil, err
+		}
+		schemaCache.snapshot = snapshot
+	}
+	return schemaCache.snapshot, nil
+}
+
+func loadSchema(ctx context.Context) (schemaSnapshot, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, `SELECT table_schema, table_name, column_name FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`)
+	if err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+	snapshot := make(schemaSnapshot)
+	var schema, table, column string
+	_, err = pgx.ForEachRow(rows, []any{&schema, &table, &column}, func() error {
+		for _, name := range []string{table, schema + "." + table} {
+			if snapshot[name] == nil {
+				snapshot[name] = make(map[string]bool)
+			}
+			snapshot[name][column] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+	return snapshot, nil
+}
+
+// simpleColumnRe matches the plain (possibly qualified and aliased) column
+// references, e.g. "name", "u.name", "u.name AS user_name".
+var simpleColumnRe = regexp.MustCompile(`^((?:"[^"]+"|\w+)\.)?("[^"]+"|\w+)(\s+(?i:AS)\s+\w+)?$`)
+
+// ValidateAgainstSchema checks that the tables and the columns referenced by
+// the query exist, against a snapshot of information_schema loaded at the
+// first call (see ReloadSchema), without executing the query. It catches the
+// typos in the names at the startup or in the tests instead of at runtime in
+// production. The checked references are the tables of FROM, JOIN, INSERT
+// INTO, UPDATE and DELETE FROM, and the plain columns of SELECT, INSERT and
+// UPDATE SET, the expressions and the conditions are not checked. All the
+// problems are reported, joined.
+//
+// Example:
+//
+//	if err := pg.ValidateAgainstSchema(ctx, usersQuery); err != nil {
+//		log.Fatal(err) // e.g. column "emial" does not exist in "users"
+//	}
+func ValidateAgainstSchema(ctx context.Context, query sq.Sqlizer) error {
+	snapshot, err := cachedSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	var refs []tableRef
+	var columns []string
+	switch query := query.(type) {
+	case sq.SelectBuilder:
+		refs = tableRefs(query)
+		if parts, ok := builder.Get(query, "Columns"); ok {
+			for _, part := range parts.([]sq.Sqlizer) {
+				if sqlstr, _, err := part.ToSql(); err == nil {
+					columns = append(columns, strings.TrimSpace(sqlstr))
+				}
+			}
+		}
+	case sq.InsertBuilder:
+		into, _ := builder.Get(query, "Into")
+		refs = []tableRef{{name: fmt.Sprint(into)}}
+		if cols, ok := builder.Get(query, "Columns"); ok {
+			columns = cols.([]string)
+		}
+	case sq.UpdateBuilder:
+		table, _ := builder.Get(query, "Table")
+		refs = []tableRef{tableRefOf(fmt.Sprint(table))}
+		if clauses, ok := builder.Get(query, "SetClauses"); ok {
+			rv := reflect.ValueOf(clauses)
+			for i := 0; i < rv.Len(); i++ {
+				columns = append(columns, rv.Index(i).FieldByName("column").String())
+			}
+		}
+	case sq.DeleteBuilder:
+		from, _ := builder.Get(query, "From")
+		refs = []tableRef{tableRefOf(fmt.Sprint(from))}
+	default:
+		return fmt.Errorf("unsupported query type %T", query)
+	}
+
+	var errs []error
+	tables := make(map[string]map[string]bool) // alias to the columns
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.name, "(") {
+			continue // subquery
+		}
+		cols, ok := snapshot[unquoteIdent(ref.name)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("table %q does not exist", ref.name))
+			continue
+		}
+		tables[unquoteIdent(ref.name)] = cols
+		if i := strings.LastIndex(ref.name, "."); i >= 0 {
+			tables[unquoteIdent(ref.name[i+1:])] = cols
+		}
+		if ref.alias != "" {
+			tables[unquoteIdent(ref.alias)] = cols
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, col := range columns {
+		m := simpleColumnRe.FindStringSubmatch(col)
+		if m == nil || m[2] == "*" {
+			continue // an expression
+		}
+		qualifier, name := unquoteIdent(strings.TrimSuffix(m[1], ".")), unquoteIdent(m[2])
+		if qualifier != "" {
+			cols, ok := tables[qualifier]
+			if !ok {
+				errs = append(errs, fmt.Errorf("table %q of column %q is not in the query", qualifier, col))
+			} else if !cols[name] {
+				errs = append(errs, fmt.Errorf("column %q does not exist in %q", name, qualifier))
+			}
+			continue
+		}
+		found := false
+		for _, cols := range tables {
+			if cols[name] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("column %q does not exist in the tables of the query", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unquoteIdent turns a (possibly quoted, schema-qualified) name into the name
+// stored in the catalog, e.g. public."User" to public.User, Users to users.
+func unquoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) && len(part) >= 2 {
+			parts[i] = strings.ReplaceAll(part[1:len(part)-1], `""`, `"`)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,189 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SeekList runs a SELECT query page by page with the seek method, i.e. the
+// rows after the cursor of the pagination in the order of the key columns,
+// which must identify the rows uniquely and be selected by the query. Prefix
+// all the key columns with "-" to list in descending order. The rows are
+// appended to vs, and the cursor of the pagination is updated to the one of
+// the next page. Returns true if there is a next page.
+//
+// Example:
+//
+//	var users []*User
+//	p := pg.NewSeekPagination(20)
+//	p.SetCursor(r.URL.Query().Get("cursor"))
+//	hasNext, err := pg.SeekList(ctx, &users, pg.SQL.Select("*").From("users"), p, "-created_at", "-id")
+//	// p.Cursor() is the cursor of the next page
+func SeekList[T any](ctx context.Context, vs *[]T, query sq.SelectBuilder, p *SeekPagination, keyColumns ...string) (bool, error) {
+	if len(keyColumns) == 0 {
+		return false, errors.New("no key columns")
+	}
+	columns, desc, err := seekColumns(keyColumns)
+	if err != nil {
+		return false, err
+	}
+
+	if p.Cursor() != "" {
+		values, err := decodeCursorValues(p.Cursor())
+		if err != nil {
+			return false, err
+		}
+		if len(values) != len(columns) {
+			return false, fmt.Errorf("%w: expect %d values, got %d", ErrInvalidCursor, len(columns), len(values))
+		}
+		query = query.Where(seekCondition(columns, values, desc))
+	}
+	for _, col := range columns {
+		if desc {
+			query = query.OrderBy(col + " DESC")
+		} else {
+			query = query.OrderBy(col)
+		}
+	}
+	limit := p.Limit()
+	query = query.Limit(uint64(limit) + 1)
+
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("assemble query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, sqlstr, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	fieldIndexes, err := seekFieldIndexes(rows.FieldDescriptions(), columns)
+	if err != nil {
+		return false, err
+	}
+
+	var (
+		scanner = scanAPI(vs).NewRowScanner(rows)
+		count   int64
+		hasNext bool
+		last    []any
+	)
+	for rows.Next() {
+		if count == limit {
+			hasNext = true
+			break
+		}
+		v, err := scanRow[T](scanner)
+		if err != nil {
+			return false, fmt.Errorf("scan row: %w", err)
+		}
+		if last, err = seekKeys(rows, fieldIndexes); err != nil {
+			return false, err
+		}
+		*vs = append(*vs, v)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	next := ""
+	if hasNext {
+		if next, err = EncodeCursor(last...); err != nil {
+			return false, err
+		}
+	}
+	p.SetCursor(next)
+	return hasNext, nil
+}
+
+// seekColumns strips the direction prefixes of the key columns.
+func seekColumns(keyColumns []string) (columns []string, desc bool, err error) {
+	desc = strings.HasPrefix(keyColumns[0], "-")
+	columns = make([]string, len(keyColumns))
+	for i, col := range keyColumns {
+		if strings.HasPrefix(col, "-") != desc {
+			return nil, false, errors.New("key columns must be in the same direction")
+		}
+		columns[i] = strings.TrimPrefix(col, "-")
+	}
+	return columns, desc, nil
+}
+
+// seekCondition returns the condition (col1, col2) > (v1, v2), or < when desc.
+func seekCondition(columns []string, values []any, desc bool) sq.Sqlizer {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return sq.Expr(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, placeholders), values...)
+}
+
+// seekFieldIndexes finds the indexes of the key columns in the result fields.
+// The table qualifiers of the key columns are ignored, e.g. "u.id" is "id".
+func seekFieldIndexes(fields []pgconn.FieldDescription, columns []string) ([]int, error) {
+	indexes := make([]int, len(columns))
+	for i, col := range columns {
+		name := col[strings.LastIndex(col, ".")+1:]
+		indexes[i] = -1
+		for j, field := range fields {
+			if field.Name == name {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 {
+			return nil, fmt.Errorf("key column %q is not selected", col)
+		}
+	}
+	return indexes, nil
+}
+
+// seekKeys returns the values of the key columns of the current row in their
+// text forms, which are encoded in the cursor as JSON strings and bound back
+// as the types of the columns, e.g. a uuid isn't encoded as an array of bytes.
+func seekKeys(rows pgx.Rows, fieldIndexes []int) ([]any, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, fmt.Errorf("read key values: %w", err)
+	}
+	var m *pgtype.Map
+	if conn := rows.Conn(); conn != nil {
+		m = conn.TypeMap()
+	} else {
+		m = pgtype.NewMap()
+	}
+	return textKeys(m, rows.FieldDescriptions(), values, fieldIndexes), nil
+}
+
+// textKeys encodes the values of the key fields in the text format. The
+// values which can't be encoded, e.g. of the unregistered types, are kept.
+func textKeys(m *pgtype.Map, fields []pgconn.FieldDescription, values []any, fieldIndexes []int) []any {
+	keys := make([]any, len(fieldIndexes))
+	for i, j := range fieldIndexes {
+		keys[i] = values[j]
+		if values[j] == nil {
+			continue
+		}
+		if text, err := m.Encode(fields[j].DataTypeOID, pgtype.TextFormatCode, values[j], nil); err == nil && text != nil {
+			keys[i] = string(text)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,64 @@
+package pg
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestSeekKeysCursorRoundTrip(t *testing.T) {
+	m := pgtype.NewMap()
+	id := [16]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	createdAt := time.Date(2024, 2, 29, 23, 59, 59, 123456000, time.UTC)
+	fields := []pgconn.FieldDescription{
+		{Name: "name", DataTypeOID: pgtype.TextOID},
+		{Name: "created_at", DataTypeOID: pgtype.TimestamptzOID},
+		{Name: "id", DataTypeOID: pgtype.UUIDOID},
+	}
+	values := []any{"John", createdAt, id}
+
+	keys := textKeys(m, fields, values, []int{1, 2})
+	cursor, err := EncodeCursor(keys...)
+	if err != nil {
+		t.Fatalf("EncodeCursor: %v", err)
+	}
+	decoded, err := decodeCursorValues(cursor)
+	if err != nil {
+		t.Fatalf("decodeCursorValues: %v", err)
+	}
+	if want := "6ba7b810-9dad-11d1-80b4-00c04fd430c8"; decoded[1] != want {
+		t.Errorf("uuid key = %#v, want %q", decoded[1], want)
+	}
+
+	// The decoded values are bound as the types of the key columns.
+	for i, oid := range []uint32{pgtype.TimestamptzOID, pgtype.UUIDOID} {
+		buf, err := m.Encode(oid, pgtype.TextFormatCode, decoded[i], nil)
+		if err != nil {
+			t.Fatalf("encode key #%d: %v", i, err)
+		}
+		var got any
+		if err := m.Scan(oid, pgtype.TextFormatCode, buf, &got); err != nil {
+			t.Fatalf("scan key #%d: %v", i, err)
+		}
+		if want := values[i+1]; !reflect.DeepEqual(got, want) && !equalTime(got, want) {
+			t.Errorf("key #%d = %#v, want %#v", i, got, want)
+		}
+	}
+}
+
+func TestTextKeysKeepsNull(t *testing.T) {
+	fields := []pgconn.FieldDescription{{Name: "deleted_at", DataTypeOID: pgtype.TimestamptzOID}}
+	keys := textKeys(pgtype.NewMap(), fields, []any{nil}, []int{0})
+	if keys[0] != nil {
+		t.Errorf("key = %#v, want nil", keys[0])
+	}
+}
+
+func equalTime(a, b any) bool {
+	ta, ok1 := a.(time.Time)
+	tb, ok2 := b.(time.Time)
+	return ok1 && ok2 && ta.Equal(tb)
+}
@@ -0,0 +1,211 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SelfTestCheck is a check of SelfTest, e.g. RequireExtensions.
+type SelfTestCheck struct {
+	name string
+	run  func(ctx context.Context, q Querier) (detail string, err error)
+}
+
+// SelfTestResult is the result of a check of SelfTest.
+type SelfTestResult struct {
+	Check  string `json:"check"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (r SelfTestResult) String() string {
+	status := "ok"
+	if !r.OK {
+		status = "FAILED"
+	}
+	if r.Detail == "" {
+		return r.Check + ": " + status
+	}
+	return fmt.Sprintf("%s: %s (%s)", r.Check, status, r.Detail)
+}
+
+// SelfTestReport is the report of SelfTest.
+type SelfTestReport struct {
+	Results []SelfTestResult `json:"results"`
+}
+
+// OK tells whether all the checks passed.
+func (r *SelfTestReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err returns the failures of the checks joined, or nil if all passed.
+func (r *SelfTestReport) Err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if !result.OK {
+			errs = append(errs, errors.New(result.String()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SelfTest verifies the database environment at the startup, so that the
+// misconfigurations surface at the boot rather than at the first query: the
+// connectivity, then the given checks. Returns the report of all the checks,
+// along with the error of the failed ones (see SelfTestReport.Err).
+//
+// Example:
+//
+//	report, err := pg.SelfTest(ctx,
+//		pg.RequireServerVersion(150000),
+//		pg.RequireExtensions("pgcrypto", "pg_trgm", "vector"),
+//		pg.RequireSetting("TimeZone", "UTC"),
+//		pg.RequireSetting("server_encoding", "UTF8"),
+//		pg.RequireSchemaPrivileges("app", "USAGE", "CREATE"),
+//	)
+//	for _, result := range report.Results {
+//		log.Print(result)
+//	}
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func SelfTest(ctx context.Context, checks ...SelfTestCheck) (*SelfTestReport, error) {
+	report := new(SelfTestReport)
+	q, release, err := querier(ctx)
+	if err == nil {
+		defer release()
+		var version string
+		if err = q.QueryRow(ctx, "SELECT version()").Scan(&version); err == nil {
+			report.Results = append(report.Results, SelfTestResult{Check: "connectivity", OK: true, Detail: version})
+		}
+	}
+	if err != nil {
+		report.Results = append(report.Results, SelfTestResult{Check: "connectivity", Detail: err.Error()})
+		for _, check := range checks {
+			report.Results = append(report.Results, SelfTestResult{Check: check.name, Detail: "skipped, not connected"})
+		}
+		return report, report.Err()
+	}
+
+	for _, check := range checks {
+		detail, err := check.run(ctx, q)
+		result := SelfTestResult{Check: check.name, OK: err == nil, Detail: detail}
+		if err != nil {
+			result.Detail = err.Error()
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, report.Err()
+}
+
+// RequireExtensions checks that the extensions are installed in the
+// database. See EnsureExtensions.
+func RequireExtensions(names ...string) SelfTestCheck {
+	return SelfTestCheck{
+		name: "extensions " + strings.Join(names, ", "),
+		run: func(ctx context.Context, q Querier) (string, error) {
+			rows, err := q.Query(ctx, "SELECT extname, extversion FROM pg_extension WHERE extname = ANY($1)", names)
+			if err != nil {
+				return "", fmt.Errorf("list extensions: %w", err)
+			}
+			installed := make(map[string]string)
+			var name, version string
+			for rows.Next() {
+				if err := rows.Scan(&name, &version); err != nil {
+					rows.Close()
+					return "", fmt.Errorf("list extensions: %w", err)
+				}
+				installed[name] = version
+			}
+			if err := rows.Err(); err != nil {
+				return "", fmt.Errorf("list extensions: %w", err)
+			}
+
+			var found, missing []string
+			for _, name := range names {
+				if version, ok := installed[name]; ok {
+					found = append(found, name+" "+version)
+				} else {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				return "", fmt.Errorf("not installed: %s", strings.Join(missing, ", "))
+			}
+			return strings.Join(found, ", "), nil
+		},
+	}
+}
+
+// RequireServerVersion checks that the server version is at least the given
+// one, in the format of server_version_num, e.g. 150000 for 15.0.
+func RequireServerVersion(minVersion int) SelfTestCheck {
+	return SelfTestCheck{
+		name: fmt.Sprintf("server version >= %d", minVersion),
+		run: func(ctx context.Context, q Querier) (string, error) {
+			var num int
+			var version string
+			err := q.QueryRow(ctx, "SELECT current_setting('server_version_num')::int, current_setting('server_version')").Scan(&num, &version)
+			if err != nil {
+				return "", fmt.Errorf("read server version: %w", err)
+			}
+			if num < minVersion {
+				return "", fmt.Errorf("server version %s is too old", version)
+			}
+			return version, nil
+		},
+	}
+}
+
+// RequireSetting checks that the run-time setting of the sessions has the
+// value, compared case-insensitively, e.g. TimeZone or server_encoding.
+func RequireSetting(name, value string) SelfTestCheck {
+	return SelfTestCheck{
+		name: "setting " + name,
+		run: func(ctx context.Context, q Querier) (string, error) {
+			var actual string
+			if err := q.QueryRow(ctx, "SELECT current_setting($1)", name).Scan(&actual); err != nil {
+				return "", fmt.Errorf("read setting: %w", err)
+			}
+			if !strings.EqualFold(actual, value) {
+				return "", fmt.Errorf("%s is %q, want %q", name, actual, value)
+			}
+			return actual, nil
+		},
+	}
+}
+
+// RequireSchemaPrivileges checks that the current user has the privileges,
+// i.e. USAGE and CREATE, on the schema.
+func RequireSchemaPrivileges(schema string, privileges ...string) SelfTestCheck {
+	return SelfTestCheck{
+		name: fmt.Sprintf("schema %s privileges %s", schema, strings.Join(privileges, ", ")),
+		run: func(ctx context.Context, q Querier) (string, error) {
+			var exists bool
+			if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&exists); err != nil {
+				return "", fmt.Errorf("check schema: %w", err)
+			}
+			if !exists {
+				return "", fmt.Errorf("schema %s does not exist", schema)
+			}
+
+			var missing []string
+			for _, privilege := range privileges {
+				var granted bool
+				if err := q.QueryRow(ctx, "SELECT has_schema_privilege($1, $2)", schema, privilege).Scan(&granted); err != nil {
+					return "", fmt.Errorf("check privilege %s: %w", privilege, err)
+				}
+				if !granted {
+					missing = append(missing, privilege)
+				}
+			}
+			if len(missing) > 0 {
+				return "", fmt.Errorf("current user lacks %s", strings.Join(missing, ", "))
+			}
+			return "", nil
+		},
+	}
+}
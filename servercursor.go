@@ -0,0 +1,119 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+var cursorSeq atomic.Int64
+
+// ServerCursor is a server-side cursor (DECLARE CURSOR) opened by OpenCursor.
+type ServerCursor[T any] struct {
+	name      string
+	fetchSize int
+	tx        pgx.Tx
+	owned     bool   // the transaction is begun by OpenCursor
+	release   func() // releases the connection of the owned transaction
+	done      bool
+}
+
+// OpenCursor declares a server-side cursor of the query, to read its rows in
+// batches of fetchSize with Fetch, for the batch jobs over giant result sets
+// where even the keyset iteration (see SeekList) is awkward, e.g. complex
+// sorts or window functions. The cursor lives in a transaction: the one
+// carried by the context (see WithinTx) if any, otherwise a transaction of
+// its own, ended by Close. Always call Close when done.
+//
+// Example:
+//
+//	cur, err := pg.OpenCursor[*Event](ctx, pg.SQL.Select("*").From("events").OrderBy("tenant_id", "created_at"), 1000)
+//	if err != nil {
+//		return err
+//	}
+//	defer cur.Close(ctx)
+//	for {
+//		events, err := cur.Fetch(ctx)
+//		if err != nil || len(events) == 0 {
+//			return err
+//		}
+//		process(events)
+//	}
+func OpenCursor[T any](ctx context.Context, query sq.SelectBuilder, fetchSize int) (*ServerCursor[T], error) {
+	if fetchSize <= 0 {
+		return nil, fmt.Errorf("invalid fetch size %d", fetchSize)
+	}
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	c := &ServerCursor[T]{
+		name:      fmt.Sprintf("pg_cursor_%d", cursorSeq.Add(1)),
+		fetchSize: fetchSize,
+		release:   func() {},
+	}
+	if scope := scopeFrom(ctx); scope != nil {
+		c.tx = scope.tx
+	} else {
+		if c.tx, c.release, err = begin(ctx); err != nil {
+			return nil, fmt.Errorf("begin transaction: %w", err)
+		}
+		c.owned = true
+	}
+
+	if _, err := withMiddlewares(c.tx).Exec(ctx, "DECLARE "+c.name+" NO SCROLL CURSOR FOR "+sqlstr, args...); err != nil {
+		c.end(ctx, false)
+		return nil, fmt.Errorf("declare cursor: %w", err)
+	}
+	return c, nil
+}
+
+// Fetch returns the next batch of the rows, or no rows if all the rows have
+// been read.
+func (c *ServerCursor[T]) Fetch(ctx context.Context) ([]T, error) {
+	if c.done {
+		return nil, nil
+	}
+	var vs []T
+	sqlstr := fmt.Sprintf("FETCH FORWARD %d FROM %s", c.fetchSize, c.name)
+	if err := scanAPI(&vs).Select(ctx, withMiddlewares(c.tx), &vs, sqlstr); err != nil {
+		return nil, fmt.Errorf("fetch from cursor: %w", err)
+	}
+	if len(vs) < c.fetchSize {
+		c.done = true
+	}
+	return vs, nil
+}
+
+// Close closes the cursor, and commits its own transaction if any.
+func (c *ServerCursor[T]) Close(ctx context.Context) error {
+	if c.tx == nil {
+		return nil // closed
+	}
+	if _, err := c.tx.Exec(ctx, "CLOSE "+c.name); err != nil {
+		c.end(ctx, false)
+		return fmt.Errorf("close cursor: %w", err)
+	}
+	return c.end(ctx, true)
+}
+
+// end ends the owned transaction, and releases its connection.
+func (c *ServerCursor[T]) end(ctx context.Context, commit bool) error {
+	tx := c.tx
+	c.tx = nil
+	if !c.owned {
+		return nil
+	}
+	defer c.release()
+	if !commit {
+		return tx.Rollback(ctx)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionsSchema is the DDL of the table backing SessionStore. Run it along
+// with the migrations of the application.
+const SessionsSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	token  text PRIMARY KEY,
+	data   bytea NOT NULL,
+	expiry timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions (expiry);
+`
+
+// SessionStore is a session store backed by the sessions table (see
+// SessionsSchema). It implements the Store and CtxStore interfaces of
+// github.com/alexedwards/scs/v2.
+//
+// Example:
+//
+//	store := pg.NewSessionStore(30*time.Minute, 5*time.Minute)
+//	defer store.StopCleanup()
+//	sessionManager := scs.New()
+//	sessionManager.Store = store
+type SessionStore struct {
+	idleTimeout time.Duration
+	stopCleanup chan struct{}
+}
+
+// NewSessionStore creates a SessionStore. When idleTimeout is positive, the
+// expiry of a session slides to now+idleTimeout every time it is found. When
+// cleanupInterval is positive, the expired sessions are deleted periodically
+// in a background goroutine, call StopCleanup to stop it.
+func NewSessionStore(idleTimeout, cleanupInterval time.Duration) *SessionStore {
+	s := &SessionStore{idleTimeout: idleTimeout}
+	if cleanupInterval > 0 {
+		s.stopCleanup = make(chan struct{})
+		go s.startCleanup(cleanupInterval)
+	}
+	return s
+}
+
+// Find returns the data of a session. Returns false if the session is not
+// found or expired.
+func (s *SessionStore) Find(token string) ([]byte, bool, error) {
+	return s.FindCtx(context.Background(), token)
+}
+
+// FindCtx is the context-aware version of Find.
+func (s *SessionStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
+
+	var data []byte
+	if s.idleTimeout > 0 {
+		err = q.QueryRow(ctx, `UPDATE sessions SET expiry = GREATEST(expiry, now() + $2::float8 * interval '1 second')
+			WHERE token = $1 AND expiry > now() RETURNING data`, token, s.idleTimeout.Seconds()).Scan(&data)
+	} else {
+		err = q.QueryRow(ctx, "SELECT data FROM sessions WHERE token = $1 AND expiry > now()", token).Scan(&data)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("find session: %w", err)
+	}
+	return data, true, nil
+}
+
+// Commit creates or updates a session.
+func (s *SessionStore) Commit(token string, data []byte, expiry time.Time) error {
+	return s.CommitCtx(context.Background(), token, data, expiry)
+}
+
+// CommitCtx is the context-aware version of Commit.
+func (s *SessionStore) CommitCtx(ctx context.Context, token string, data []byte, expiry time.Time) error {
+	query := SQL.Insert("sessions").
+		Columns("token", "data", "expiry").
+		Values(token, data, expiry).
+		Suffix("ON CONFLICT (token) DO UPDATE SET data = excluded.data, expiry = excluded.expiry")
+	if _, err := Exec(ctx, query); err != nil {
+		return fmt.Errorf("commit session: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a session.
+func (s *SessionStore) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is the context-aware version of Delete.
+func (s *SessionStore) DeleteCtx(ctx context.Context, token string) error {
+	if _, err := Exec(ctx, SQL.Delete("sessions").Where("token = ?", token)); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// All returns the data of all the active sessions, keyed by token.
+func (s *SessionStore) All() (map[string][]byte, error) {
+	return s.AllCtx(context.Background())
+}
+
+// AllCtx is the context-aware version of All.
+func (s *SessionStore) AllCtx(ctx context.Context) (map[string][]byte, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rows, err := q.Query(ctx, "SELECT token, data FROM sessions WHERE expiry > now()")
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	sessions := make(map[string][]byte)
+	var token string
+	var data []byte
+	_, err = pgx.ForEachRow(rows, []any{&token, &data}, func() error {
+		sessions[token] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteExpired deletes the expired sessions.
+func (s *SessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return Exec(ctx, SQL.Delete("sessions").Where("expiry <= now()"))
+}
+
+// StopCleanup stops the background cleanup goroutine.
+func (s *SessionStore) StopCleanup() {
+	if s.stopCleanup != nil {
+		close(s.stopCleanup)
+	}
+}
+
+func (s *SessionStore) startCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.DeleteExpired(context.Background()) // retry on next tick on failure
+		case <-s.stopCleanup:
+			return
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package pg
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// shadowTimeout bounds the duration of a mirrored query.
+const shadowTimeout = 30 * time.Second
+
+// Divergence describes a read query whose result on the shadow database
+// differs from the result on the primary database.
+type Divergence struct {
+	SQL     string
+	Args    []any
+	Primary [][]any // rows returned by the primary database
+	Shadow  [][]any // rows returned by the shadow database
+	Err     error   // error of the shadow query, if any
+
+	// Incomplete reports that the primary rows were closed before read to
+	// the end, thus only the rows read are compared with the first rows of
+	// the shadow database.
+	Incomplete bool
+}
+
+// Shadow returns a QueryMiddleware that mirrors a ratio (0.0~1.0) of the read
+// (SELECT) queries, including the ones of QueryRow, to a second database, e.g.
+// a pool connected to a new schema or a new cluster. The results are compared
+// asynchronously after the primary rows are consumed or closed, and
+// divergences are reported by calling report. The primary query is never
+// affected by the shadow one.
+//
+// Usage: de-risking large data migrations between schemas or clusters.
+//
+// Example:
+//
+//	pg.Use(pg.Shadow(newPool, 0.1, func(d pg.Divergence) {
+//		log.Printf("shadow divergence: %s %v", d.SQL, d.Err)
+//	}))
+func Shadow(shadow Querier, ratio float64, report func(Divergence)) QueryMiddleware {
+	mirrored := func(sql string) bool {
+		if rand.Float64() >= ratio {
+			return false
+		}
+		_, operation := parseTableOperation(sql)
+		return operation == "SELECT"
+	}
+	wrap := func(rows pgx.Rows, sql string, args []any) *shadowRows {
+		return &shadowRows{Rows: rows, onDone: func(primary [][]any, complete bool) {
+			go compareShadow(shadow, sql, args, primary, complete, report)
+		}}
+	}
+
+	return func(next Querier) Querier {
+		return &QuerierFuncs{
+			Next: next,
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				rows, err := next.Query(ctx, sql, args...)
+				if err != nil || !mirrored(sql) {
+					return rows, err
+				}
+				return wrap(rows, sql, args), nil
+			},
+			QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+				if !mirrored(sql) {
+					return next.QueryRow(ctx, sql, args...)
+				}
+				// Query the rows to capture their values, as pgx does for
+				// QueryRow.
+				rows, err := next.Query(ctx, sql, args...)
+				if err != nil {
+					return &shadowRow{err: err}
+				}
+				return &shadowRow{rows: wrap(rows, sql, args)}
+			},
+		}
+	}
+}
+
+// compareShadow runs the query on the shadow database and compares its rows
+// with the primary ones. If the primary rows are incomplete, they are compared
+// with as many rows of the shadow database.
+func compareShadow(shadow Querier, sql string, args []any, primary [][]any, complete bool, report func(Divergence)) {
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	defer cancel()
+
+	limit := -1
+	if !complete {
+		limit = len(primary)
+	}
+	d := Divergence{SQL: sql, Args: args, Primary: primary, Incomplete: !complete}
+	rows, err := shadow.Query(ctx, sql, args...)
+	d.Shadow, d.Err = collectValues(rows, err, limit)
+	if d.Err != nil || !reflect.DeepEqual(d.Primary, d.Shadow) {
+		report(d)
+	}
+}
+
+// collectValues collects the values of up to limit rows, or all the rows if
+// limit is negative.
+func collectValues(rows pgx.Rows, err error, limit int) ([][]any, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result [][]any
+	for len(result) != limit && rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, values)
+	}
+	return result, rows.Err()
+}
+
+// shadowRows captures the values of the rows while they are being consumed.
+// onDone is called once the rows are consumed or closed without error,
+// complete tells whether all the rows were consumed.
+type shadowRows struct {
+	pgx.Rows
+	captured [][]any
+	failed   bool
+	done     bool
+	onDone   func(captured [][]any, complete bool)
+}
+
+func (r *shadowRows) Next() bool {
+	if !r.Rows.Next() {
+		r.finish(true)
+		return false
+	}
+	values, err := r.Rows.Values()
+	if err != nil {
+		r.failed = true
+	}
+	r.captured = append(r.captured, values)
+	return true
+}
+
+func (r *shadowRows) Close() {
+	r.Rows.Close()
+	r.finish(false)
+}
+
+func (r *shadowRows) finish(complete bool) {
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.failed || r.Rows.Err() != nil {
+		return
+	}
+	if !complete && len(r.captured) == 0 {
+		return // closed before any row is read, nothing to compare
+	}
+	r.onDone(r.captured, complete)
+}
+
+// shadowRow is the row of QueryRow read from the shadowed rows.
+type shadowRow struct {
+	rows *shadowRows
+	err  error
+}
+
+func (r *shadowRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	rows := r.rows
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	// Read to the end, thus a single row is compared as complete.
+	rows.Next()
+	return rows.Err()
+}
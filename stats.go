@@ -0,0 +1,187 @@
+package pg
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// statsSampleSize is the number of recent latencies kept per (table, operation)
+// to compute the percentiles.
+const statsSampleSize = 1024
+
+// QueryStats holds the aggregated statistics of the queries against a table
+// with an operation, e.g. ("users", "SELECT").
+type QueryStats struct {
+	Table     string        `json:"table"`
+	Operation string        `json:"operation"`
+	Count     int64         `json:"count"`
+	Errors    int64         `json:"errors"`
+	ErrorRate float64       `json:"error_rate"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+}
+
+type statsKey struct {
+	table     string
+	operation string
+}
+
+type statsEntry struct {
+	count     int64
+	errors    int64
+	latencies []time.Duration // ring buffer
+	next      int
+}
+
+func (e *statsEntry) observe(latency time.Duration, failed bool) {
+	e.count++
+	if failed {
+		e.errors++
+	}
+	if len(e.latencies) < statsSampleSize {
+		e.latencies = append(e.latencies, latency)
+		return
+	}
+	e.latencies[e.next] = latency
+	e.next = (e.next + 1) % statsSampleSize
+}
+
+type statsRegistry struct {
+	mu      sync.Mutex
+	entries map[statsKey]*statsEntry
+}
+
+var stats = &statsRegistry{entries: make(map[statsKey]*statsEntry)}
+
+func (r *statsRegistry) observe(sql string, latency time.Duration, failed bool) {
+	table, operation := parseTableOperation(sql)
+	key := statsKey{table, operation}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[key]
+	if !ok {
+		entry = new(statsEntry)
+		r.entries[key] = entry
+	}
+	entry.observe(latency, failed)
+}
+
+// Stats returns a snapshot of the query statistics aggregated by (table,
+// operation), sorted by the count of queries in descending order. The
+// percentiles are computed from the most recent queries.
+func Stats() []QueryStats {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	result := make([]QueryStats, 0, len(stats.entries))
+	for key, entry := range stats.entries {
+		latencies := make([]time.Duration, len(entry.latencies))
+		copy(latencies, entry.latencies)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		s := QueryStats{
+			Table:     key.table,
+			Operation: key.operation,
+			Count:     entry.count,
+			Errors:    entry.errors,
+			P50:       percentile(latencies, 0.50),
+			P90:       percentile(latencies, 0.90),
+			P99:       percentile(latencies, 0.99),
+		}
+		if entry.count > 0 {
+			s.ErrorRate = float64(entry.errors) / float64(entry.count)
+		}
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Table != result[j].Table {
+			return result[i].Table < result[j].Table
+		}
+		return result[i].Operation < result[j].Operation
+	})
+	return result
+}
+
+// ResetStats clears all the query statistics.
+func ResetStats() {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.entries = make(map[statsKey]*statsEntry)
+}
+
+// percentile returns the p-th percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+var (
+	reOperation   = regexp.MustCompile(`^\s*(\w+)`)
+	reSelectTable = regexp.MustCompile(`(?i)\bFROM\s+([\w."]+)`)
+	reInsertTable = regexp.MustCompile(`(?i)^\s*INSERT\s+INTO\s+([\w."]+)`)
+	reUpdateTable = regexp.MustCompile(`(?i)^\s*UPDATE\s+(?:ONLY\s+)?([\w."]+)`)
+	reDeleteTable = regexp.MustCompile(`(?i)^\s*DELETE\s+FROM\s+(?:ONLY\s+)?([\w."]+)`)
+)
+
+// parseTableOperation extracts the (primary) table name and the operation
+// from the SQL generated by the query builders. An empty table name is
+// returned when it can't be determined, e.g. selecting from a subquery.
+func parseTableOperation(sql string) (table, operation string) {
+	if m := reOperation.FindStringSubmatch(sql); m != nil {
+		operation = strings.ToUpper(m[1])
+	}
+
+	var re *regexp.Regexp
+	switch operation {
+	case "SELECT":
+		re = reSelectTable
+	case "INSERT":
+		re = reInsertTable
+	case "UPDATE":
+		re = reUpdateTable
+	case "DELETE":
+		re = reDeleteTable
+	default:
+		return "", operation
+	}
+	if m := re.FindStringSubmatch(sql); m != nil {
+		table = strings.ReplaceAll(m[1], `"`, "")
+	}
+	return table, operation
+}
+
+// statsTracer is a pgx.QueryTracer feeding the stats registry.
+type statsTracer struct{}
+
+type statsTraceKey struct{}
+
+type statsTraceData struct {
+	sql   string
+	start time.Time
+}
+
+func (statsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, statsTraceKey{}, &statsTraceData{data.SQL, time.Now()})
+}
+
+func (statsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(statsTraceKey{}).(*statsTraceData)
+	if !ok {
+		return
+	}
+	stats.observe(trace.sql, time.Since(trace.start), data.Err != nil)
+}
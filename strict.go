@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lann/builder"
+)
+
+// ErrStrict is returned in the strict mode when the options of a List call
+// conflict or don't apply. See SetStrict.
+var ErrStrict = errors.New("pg: strict mode")
+
+var strict bool
+
+// SetStrict enables or disables (default) the strict mode, in which List
+// returns ErrStrict instead of silently producing surprising SQL when:
+//   - the result is sorted by the same column more than once;
+//   - a filter (e.g. With) is on a column qualified with a table or alias not
+//     in the FROM or JOIN clauses of the query.
+//
+// Usage: enable it in the development and the tests.
+func SetStrict(enabled bool) {
+	strict = enabled
+}
+
+// checkStrict checks the options of a List call in the strict mode.
+func checkStrict(query sq.SelectBuilder, filteringOpts, sortingOpts []ListOption) error {
+	sorted := make(map[string]bool)
+	for _, opt := range sortingOpts {
+		for _, sortBy := range sortKeysOf(opt) {
+			if sortBy.columnName == "" {
+				continue
+			}
+			if sorted[sortBy.columnName] {
+				return fmt.Errorf("%w: sorted by %q more than once", ErrStrict, sortBy.columnName)
+			}
+			sorted[sortBy.columnName] = true
+		}
+	}
+
+	var aliases map[string]bool
+	for _, opt := range filteringOpts {
+		filter, ok := opt.(*columnFilterOption)
+		if !ok {
+			continue
+		}
+		i := strings.LastIndex(filter.column, ".")
+		if i < 0 {
+			continue
+		}
+		if aliases == nil {
+			aliases = tableAliases(query)
+		}
+		if qualifier := filter.column[:i]; !aliases[qualifier] {
+			return fmt.Errorf("%w: filter on %q, table %q is not in the query", ErrStrict, filter.column, qualifier)
+		}
+	}
+	return nil
+}
+
+// tableAliases returns the names and the aliases of the tables in the FROM and
+// JOIN clauses of the query, e.g. "users", "u" of "users AS u".
+func tableAliases(query sq.SelectBuilder) map[string]bool {
+	aliases := make(map[string]bool)
+	for _, ref := range tableRefs(query) {
+		aliases[ref.name] = true
+		if i := strings.LastIndex(ref.name, "."); i >= 0 {
+			aliases[ref.name[i+1:]] = true // schema-qualified
+		}
+		if ref.alias != "" {
+			aliases[ref.alias] = true
+		}
+	}
+	return aliases
+}
+
+type tableRef struct {
+	name  string
+	alias string
+}
+
+// tableRefs returns the tables in the FROM and JOIN clauses of the query.
+func tableRefs(query sq.SelectBuilder) []tableRef {
+	var clauses []string
+	if from, err := selectFromTable(query); err == nil {
+		clauses = append(clauses, strings.Split(from, ",")...)
+	}
+	if joins, ok := builder.Get(query, "Joins"); ok {
+		for _, join := range joins.([]sq.Sqlizer) {
+			sqlstr, _, err := join.ToSql()
+			if err != nil {
+				continue
+			}
+			// e.g. "LEFT JOIN orgs o ON ..."
+			if i := strings.Index(strings.ToUpper(sqlstr), "JOIN "); i >= 0 {
+				sqlstr = sqlstr[i+len("JOIN "):]
+			}
+			if i := strings.Index(strings.ToUpper(sqlstr), " ON "); i >= 0 {
+				sqlstr = sqlstr[:i]
+			}
+			clauses = append(clauses, sqlstr)
+		}
+	}
+
+	var refs []tableRef
+	for _, clause := range clauses {
+		if ref := tableRefOf(clause); ref.name != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// tableRefOf parses a table reference, e.g. "users AS u".
+func tableRefOf(clause string) tableRef {
+	fields := strings.Fields(clause)
+	if len(fields) == 0 {
+		return tableRef{}
+	}
+	ref := tableRef{name: fields[0]}
+	if len(fields) > 1 {
+		ref.alias = fields[len(fields)-1]
+	}
+	return ref
+}
@@ -0,0 +1,303 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// SyncSetResult reports the changes made by SyncSet.
+type SyncSetResult struct {
+	Inserted int64
+	Updated  int64
+	Deleted  int64
+}
+
+// SyncSet reconciles the rows of a table within the given scope to match the
+// desired rows. It diffs the desired rows against the current rows in a
+// transaction and performs the minimal set of INSERT/UPDATE/DELETE operations.
+//
+// The scope columns are merged into every inserted row. Rows are matched by
+// keyColumns, when no key columns are specified, all the columns of the
+// desired rows (except the scope columns) are used, i.e. rows are only
+// inserted or deleted, never updated. All the desired rows must have the same
+// set of columns.
+//
+// The rows are matched and compared in SQL, where the desired values are cast
+// to the types of the columns, e.g. a uuid given as a string matches the
+// stored uuid.
+//
+// Usage: maintaining join tables (tags, memberships) from API payloads.
+//
+// Example:
+//
+//	result, err := pg.SyncSet(ctx, "user_tags", sq.Eq{"user_id": 1}, []map[string]any{
+//		{"tag": "go"},
+//		{"tag": "postgres"},
+//	})
+//
+// If the context carries a transaction (see WithinTx), SyncSet runs in it.
+func SyncSet(ctx context.Context, table string, scopeCond sq.Eq, desiredRows []map[string]any, keyColumns ...string) (result *SyncSetResult, err error) {
+	err = WithinTx(ctx, func(ctx context.Context) error {
+		result, err = syncSet(ctx, table, scopeCond, desiredRows, keyColumns)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func syncSet(ctx context.Context, table string, scopeCond sq.Eq, desiredRows []map[string]any, keyColumns []string) (*SyncSetResult, error) {
+	table = QuoteIdent(table)
+	columns, err := syncSetColumns(scopeCond, desiredRows)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyColumns) == 0 {
+		keyColumns = columns
+	}
+	valueColumns := subtractColumns(columns, keyColumns)
+
+	desiredKeys := make(map[string]bool, len(desiredRows))
+	for _, row := range desiredRows {
+		key, err := syncSetKey(row, keyColumns)
+		if err != nil {
+			return nil, err
+		}
+		if desiredKeys[key] {
+			return nil, fmt.Errorf("duplicate desired row: %v", row)
+		}
+		desiredKeys[key] = true
+	}
+
+	result := new(SyncSetResult)
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Nothing is desired, clear the scope.
+	if len(desiredRows) == 0 {
+		sqlstr, args, err := SQL.Delete(table).Where(scopeCond).ToSql()
+		if err != nil {
+			return nil, fmt.Errorf("assemble delete query: %w", err)
+		}
+		tag, err := q.Exec(ctx, sqlstr, args...)
+		if err != nil {
+			return nil, fmt.Errorf("delete rows: %w", err)
+		}
+		result.Deleted = tag.RowsAffected()
+		return result, nil
+	}
+
+	types, err := columnTypes(ctx, q, table)
+	if err != nil {
+		return nil, err
+	}
+	scopeColumns := sortedKeys(scopeCond)
+	for _, col := range append(append([]string(nil), scopeColumns...), columns...) {
+		if _, ok := types[col]; !ok {
+			return nil, fmt.Errorf("table %s has no column %q", table, col)
+		}
+	}
+
+	// The current rows are locked, thus the changes below are based on them.
+	if _, err := execQuery(ctx, q, SQL.Select("1").From(table).Where(scopeCond).Suffix("FOR UPDATE")); err != nil {
+		return nil, fmt.Errorf("lock current rows: %w", err)
+	}
+
+	// The rows are matched and diffed in SQL, where the desired values are
+	// cast to the types of the columns, e.g. a uuid or a numeric compares
+	// equal regardless of its representation in Go.
+	desired := syncSetDesired(desiredRows, columns, types)
+	scope := qualifyEq("t", scopeCond)
+	match := matchColumns("t", "d", keyColumns)
+
+	deletion := SQL.Delete(table + " AS t").PrefixExpr(desired).
+		Where(scope).
+		Where("NOT EXISTS (SELECT 1 FROM desired d WHERE " + match + ")")
+	if result.Deleted, err = execQuery(ctx, q, deletion); err != nil {
+		return nil, fmt.Errorf("delete rows: %w", err)
+	}
+
+	if len(valueColumns) > 0 {
+		update := SQL.Update(table + " AS t").PrefixExpr(desired)
+		current := make([]string, len(valueColumns))
+		values := make([]string, len(valueColumns))
+		for i, col := range valueColumns {
+			col = QuoteIdent(col)
+			update = update.Set(col, sq.Expr("d."+col))
+			current[i] = "t." + col
+			values[i] = "d." + col
+		}
+		update = update.From("desired d").
+			Where(scope).
+			Where(match).
+			Where("ROW(" + strings.Join(current, ", ") + ") IS DISTINCT FROM ROW(" + strings.Join(values, ", ") + ")")
+		if result.Updated, err = execQuery(ctx, q, update); err != nil {
+			return nil, fmt.Errorf("update rows: %w", err)
+		}
+	}
+
+	insertColumns := make([]string, 0, len(scopeColumns)+len(columns))
+	selection := SQL.Select().PlaceholderFormat(sq.Question)
+	for _, col := range scopeColumns {
+		insertColumns = append(insertColumns, QuoteIdent(col))
+		selection = selection.Column(sq.Expr("?::"+types[col], scopeCond[col]))
+	}
+	for _, col := range columns {
+		insertColumns = append(insertColumns, QuoteIdent(col))
+		selection = selection.Column("d." + QuoteIdent(col))
+	}
+	existing := SQL.Select("1").From(table + " AS t").Where(scope).Where(match).PlaceholderFormat(sq.Question)
+	selection = selection.From("desired d").
+		Where(sq.Expr("NOT EXISTS (?)", existing)).
+		// Keep the insertion order as the desired rows.
+		OrderBy("d.syncset_ord")
+	insertion := SQL.Insert(table).PrefixExpr(desired).Columns(insertColumns...).Select(selection)
+	if result.Inserted, err = execQuery(ctx, q, insertion); err != nil {
+		return nil, fmt.Errorf("insert rows: %w", err)
+	}
+
+	return result, nil
+}
+
+// syncSetDesired returns the CTE "desired" of the desired rows, whose values
+// are cast to the types of the columns. The ordinal of a row is in the
+// syncset_ord column.
+func syncSetDesired(desiredRows []map[string]any, columns []string, types map[string]string) sq.Sqlizer {
+	var b strings.Builder
+	b.WriteString("WITH desired (syncset_ord")
+	for _, col := range columns {
+		b.WriteString(", " + QuoteIdent(col))
+	}
+	b.WriteString(") AS (VALUES ")
+	args := make([]any, 0, len(desiredRows)*len(columns))
+	for i, row := range desiredRows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "(%d", i)
+		for _, col := range columns {
+			b.WriteString(", ?::" + types[col])
+			args = append(args, row[col])
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(")")
+	return sq.Expr(b.String(), args...)
+}
+
+// columnTypes returns the types of the columns of the table, by the names of
+// the columns.
+func columnTypes(ctx context.Context, q Querier, table string) (map[string]string, error) {
+	rows, err := q.Query(ctx, `SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, table)
+	if err != nil {
+		return nil, fmt.Errorf("load columns of %s: %w", table, err)
+	}
+	types := make(map[string]string)
+	var name, typ string
+	if _, err := pgx.ForEachRow(rows, []any{&name, &typ}, func() error {
+		types[name] = typ
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("load columns of %s: %w", table, err)
+	}
+	return types, nil
+}
+
+// qualifyEq qualifies the columns of eq with the alias of the table.
+func qualifyEq(alias string, eq sq.Eq) sq.Eq {
+	qualified := make(sq.Eq, len(eq))
+	for col, value := range eq {
+		qualified[alias+"."+QuoteIdent(col)] = value
+	}
+	return qualified
+}
+
+// matchColumns returns the condition matching the rows of the two aliases by
+// the columns, NULLs match each other.
+func matchColumns(left, right string, columns []string) string {
+	conds := make([]string, len(columns))
+	for i, col := range columns {
+		col = QuoteIdent(col)
+		conds[i] = left + "." + col + " IS NOT DISTINCT FROM " + right + "." + col
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// syncSetColumns returns the sorted columns of the desired rows, excluding the
+// scope columns. All the rows must have the same set of columns.
+func syncSetColumns(scopeCond sq.Eq, desiredRows []map[string]any) ([]string, error) {
+	if len(desiredRows) == 0 {
+		return nil, nil
+	}
+
+	var columns []string
+	for col := range desiredRows[0] {
+		if _, ok := scopeCond[col]; !ok {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	if len(columns) == 0 {
+		return nil, errors.New("desired rows have no columns besides the scope columns")
+	}
+
+	for _, row := range desiredRows[1:] {
+		for _, col := range columns {
+			if _, ok := row[col]; !ok {
+				return nil, fmt.Errorf("desired rows have different columns: %v", row)
+			}
+		}
+	}
+	return columns, nil
+}
+
+// syncSetKey builds a comparable key of the desired row from the values of the
+// key columns, to detect the duplicate desired rows.
+func syncSetKey(row map[string]any, keyColumns []string) (string, error) {
+	parts := make([]string, len(keyColumns))
+	for i, col := range keyColumns {
+		value, ok := row[col]
+		if !ok {
+			return "", fmt.Errorf("missing key column %q in row: %v", col, row)
+		}
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+func subtractColumns(columns, excluded []string) []string {
+	var result []string
+	for _, col := range columns {
+		found := false
+		for _, ex := range excluded {
+			if col == ex {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+func sortedKeys(m sq.Eq) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// The columns of the entities with validity periods, i.e. each row is a
+// version of the entity identified by the key column, which is valid in the
+// period [valid_from, valid_to). A NULL valid_to means valid until further
+// notice.
+const (
+	TemporalKeyColumn  = "id"
+	TemporalFromColumn = "valid_from"
+	TemporalToColumn   = "valid_to"
+)
+
+// GetValidAt gets the version of the entity valid at the given time from the
+// table. Returns nil if no version is valid at that time.
+//
+// Example:
+//
+//	var price = new(Price)
+//	price, err = pg.GetValidAt(ctx, price, "prices", productID, orderedAt)
+func GetValidAt[T any](ctx context.Context, v *T, table string, id any, t time.Time) (*T, error) {
+	query := SQL.Select("*").From(QuoteIdent(table)).
+		Where(sq.Eq{TemporalKeyColumn: id}).
+		Where(validAt(t))
+	return Get(ctx, v, query)
+}
+
+func validAt(t time.Time) sq.Sqlizer {
+	return sq.And{
+		sq.LtOrEq{TemporalFromColumn: t},
+		sq.Or{sq.Eq{TemporalToColumn: nil}, sq.Gt{TemporalToColumn: t}},
+	}
+}
+
+// CloseAndInsert closes the version of the entity valid at the given time
+// (sets its valid_to to at), and inserts a new version with the row values,
+// valid from at until the end of the closed version, atomically. If no
+// version is valid at that time, the new version is valid until the next
+// version if any, otherwise until further notice, thus the validity periods
+// never overlap. The key and validity columns of the row are set
+// automatically. If the context carries a transaction (see WithinTx), it runs
+// in it.
+//
+// The concurrent calls for the same entity may still insert overlapping
+// versions, add the exclusion constraint (see EnsureNoOverlap) to reject them.
+//
+// Example:
+//
+//	err := pg.CloseAndInsert(ctx, "prices", productID, time.Now(), map[string]any{"amount": 1999})
+func CloseAndInsert(ctx context.Context, table string, id any, at time.Time, row map[string]any) error {
+	return WithinTx(ctx, func(ctx context.Context) error {
+		return closeAndInsert(ctx, table, id, at, row)
+	})
+}
+
+func closeAndInsert(ctx context.Context, table string, id any, at time.Time, row map[string]any) error {
+	table = QuoteIdent(table)
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// The WHERE clause is the validity at the given time, the returned valid_to
+	// is the original one.
+	sqlstr, args, err := SQL.Update(table+" AS t").
+		Set(TemporalToColumn, at).
+		From(table + " AS o").
+		Where(sq.Expr("t.ctid = o.ctid")).
+		Where(sq.Eq{"t." + TemporalKeyColumn: id}).
+		Where(sq.LtOrEq{"t." + TemporalFromColumn: at}).
+		Where(sq.Or{sq.Eq{"t." + TemporalToColumn: nil}, sq.Gt{"t." + TemporalToColumn: at}}).
+		Suffix("RETURNING o." + TemporalToColumn).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("assemble update query: %w", err)
+	}
+	var validTo *time.Time
+	err = q.QueryRow(ctx, sqlstr, args...).Scan(&validTo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Valid until the next version, not to overlap it.
+		sqlstr, args, err = SQL.Select("MIN(" + TemporalFromColumn + ")").From(table).
+			Where(sq.Eq{TemporalKeyColumn: id}).
+			Where(sq.Gt{TemporalFromColumn: at}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("assemble select query: %w", err)
+		}
+		if err := q.QueryRow(ctx, sqlstr, args...).Scan(&validTo); err != nil {
+			return fmt.Errorf("select next version: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("close version: %w", err)
+	}
+
+	values := make(map[string]any, len(row)+3)
+	for col, value := range row {
+		values[col] = value
+	}
+	values[TemporalKeyColumn] = id
+	values[TemporalFromColumn] = at
+	values[TemporalToColumn] = validTo
+	if _, err := execQuery(ctx, q, SQL.Insert(table).SetMap(values)); err != nil {
+		return fmt.Errorf("insert version: %w", err)
+	}
+	return nil
+}
+
+// NoOverlapConstraint returns the DDL adding an exclusion constraint to the
+// table, which rejects the versions of the same entity with overlapping
+// validity periods. It requires the btree_gist extension.
+func NoOverlapConstraint(table string) string {
+	return fmt.Sprintf(
+		"ALTER TABLE %s ADD CONSTRAINT %s EXCLUDE USING gist (%s WITH =, tstzrange(%s, %s) WITH &&)",
+		QuoteIdent(table), noOverlapConstraintName(table), TemporalKeyColumn, TemporalFromColumn, TemporalToColumn,
+	)
+}
+
+// EnsureNoOverlap adds the exclusion constraint of NoOverlapConstraint to the
+// table (and creates the btree_gist extension) if it doesn't exist. A write
+// violating the constraint fails with an exclusion violation error.
+func EnsureNoOverlap(ctx context.Context, table string) error {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := q.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS btree_gist"); err != nil {
+		return fmt.Errorf("create extension btree_gist: %w", err)
+	}
+
+	var exists bool
+	err = q.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = $1::regclass AND conname = $2)",
+		QuoteIdent(table), noOverlapConstraintName(table),
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("check constraint: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := q.Exec(ctx, NoOverlapConstraint(table)); err != nil {
+		return fmt.Errorf("add constraint: %w", err)
+	}
+	return nil
+}
+
+func noOverlapConstraintName(table string) string {
+	return sanitizeIdentifier(table) + "_no_overlap"
+}
+
+// sanitizeIdentifier turns a (possibly schema-qualified) name into a plain
+// identifier, e.g. "public.prices" to "public_prices".
+func sanitizeIdentifier(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
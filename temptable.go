@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// tempTableRe matches the DDL creating a temporary table.
+var tempTableRe = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:LOCAL\s+|GLOBAL\s+)?TEMP(?:ORARY)?\s+TABLE\s`)
+
+// WithTempTable creates a temporary table by the DDL, loads it with load,
+// e.g. BulkInsert of 50k external IDs over COPY, then calls use to run the
+// queries joining against it, e.g. "filter by these IDs". All happen in a
+// transaction (see WithinTx), on the connection of the session owning the
+// temporary table, and the table is dropped at the commit, i.e. ON COMMIT
+// DROP is appended to the DDL unless it has an ON COMMIT clause.
+//
+// Example:
+//
+//	var orders []*Order
+//	err := pg.WithTempTable(ctx, "CREATE TEMP TABLE ext_ids (id text PRIMARY KEY)",
+//		func(ctx context.Context) error {
+//			_, err := pg.BulkInsert(ctx, "ext_ids", ids) // ids []ExtID
+//			return err
+//		},
+//		func(ctx context.Context) error {
+//			_, err := pg.List(ctx, orders, pg.SQL.Select("o.*").From("orders o").Join("ext_ids e ON e.id = o.external_id"))
+//			return err
+//		},
+//	)
+func WithTempTable(ctx context.Context, ddl string, load, use func(ctx context.Context) error) error {
+	if !tempTableRe.MatchString(ddl) {
+		return errors.New("ddl must be CREATE TEMP TABLE")
+	}
+	if !strings.Contains(strings.ToUpper(ddl), "ON COMMIT") {
+		ddl = strings.TrimRight(strings.TrimSpace(ddl), ";") + " ON COMMIT DROP"
+	}
+
+	return WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := Exec(ctx, sq.Expr(ddl)); err != nil {
+			return fmt.Errorf("create temp table: %w", err)
+		}
+		if err := load(ctx); err != nil {
+			return fmt.Errorf("load temp table: %w", err)
+		}
+		return use(ctx)
+	})
+}
@@ -0,0 +1,44 @@
+package pg
+
+import (
+	sq "github.com/Masterminds/squirrel"
+)
+
+// WithSimilarTo returns a ListOption that filters the rows whose column is
+// similar to the term by the trigrams (pg_trgm), i.e. similarity(column, $1)
+// > threshold, for the typo-tolerant lookups. The similarity ranges from 0 to
+// 1, pg_trgm.similarity_threshold is 0.3 by default.
+//
+// When the given term is empty, the returned ListOption is a no-op.
+//
+// Example:
+//
+//	pagination, err := pg.List(ctx, products, pg.SQL.Select("*").From("products"),
+//		pg.WithSimilarTo("name", term, 0.3),
+//		pg.WithSortBySimilarity("name", term),
+//	)
+func WithSimilarTo(columnName, term string, threshold float64) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if term == "" {
+			return sb
+		}
+		return sb.Where("similarity("+columnName+", ?) > ?", term, threshold)
+	})
+}
+
+// WithSortBySimilarity returns a ListOption that sorts the result by the
+// similarity of the column to the term, the most similar first. See
+// WithSimilarTo.
+//
+// When the given term is empty, the returned ListOption is a no-op.
+func WithSortBySimilarity(columnName, term string) ListOption {
+	if term == "" {
+		return &withSortByOption{}
+	}
+	return &withSortByOption{
+		columnName: "similarity(" + columnName + ", ?)",
+		direction:  "desc",
+		args:       []any{term},
+	}
+}
@@ -0,0 +1,178 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type txScopeKey struct{}
+
+// txScope is the scope of a transaction started by WithinTx, carried by the
+// context.
+type txScope struct {
+	tx pgx.Tx
+
+	mu         sync.Mutex
+	onCommit   []func(context.Context)
+	onRollback []func(context.Context)
+}
+
+// hooks returns the callbacks registered for the outcome of the transaction.
+func (s *txScope) hooks(committed bool) []func(context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if committed {
+		return s.onCommit
+	}
+	return s.onRollback
+}
+
+func scopeFrom(ctx context.Context) *txScope {
+	scope, _ := ctx.Value(txScopeKey{}).(*txScope)
+	return scope
+}
+
+// WithinTx runs fn in a transaction, which is committed if fn returns nil, or
+// rolled back otherwise (including panics). The transaction is carried by the
+// context passed to fn, all the helpers (Get, List, Exec, etc.) called with
+// that context run in the transaction instead of on the pool. A nested
+// WithinTx joins the outer transaction. See also AfterCommit and
+// AfterRollback.
+//
+// Example:
+//
+//	err := pg.WithinTx(ctx, func(ctx context.Context) error {
+//		if _, err := pg.Exec(ctx, pg.SQL.Update("accounts").Set("balance", sq.Expr("balance - ?", 100)).Where(sq.Eq{"id": 1})); err != nil {
+//			return err
+//		}
+//		_, err := pg.Exec(ctx, pg.SQL.Update("accounts").Set("balance", sq.Expr("balance + ?", 100)).Where(sq.Eq{"id": 2}))
+//		return err
+//	})
+func WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if scope := scopeFrom(ctx); scope != nil {
+		return fn(ctx)
+	}
+
+	tx, release, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer release()
+
+	if err := applyProfile(ctx, tx); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	scope := &txScope{tx: tx}
+	committed := false
+	defer func() {
+		// Also on panics.
+		if !committed {
+			tx.Rollback(ctx)
+		}
+		for _, hook := range scope.hooks(committed) {
+			hook(ctx)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txScopeKey{}, scope)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// txBeginner is implemented by the queriers able to begin transactions, e.g.
+// *pgx.Conn and pgx.Tx (a nested transaction, i.e. a savepoint).
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// begin begins a transaction on the querier carried by the context (see
+// WithQuerier) if any, otherwise on a connection acquired from the pool. Call
+// release after the transaction is done.
+func begin(ctx context.Context) (tx pgx.Tx, release func(), err error) {
+	if q := querierFrom(ctx); q != nil {
+		b, ok := q.(txBeginner)
+		if !ok {
+			return nil, nil, fmt.Errorf("querier %T can't begin transactions", q)
+		}
+		tx, err = b.Begin(ctx)
+		return tx, func() {}, err
+	}
+
+	conn, err := acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tx, err = conn.Begin(ctx); err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+	return tx, conn.Release, nil
+}
+
+// RunInTx is like WithinTx, but also passes the transaction to fn, for
+// running queries on it directly.
+//
+// Example:
+//
+//	err := pg.RunInTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+//		if _, err := tx.Exec(ctx, "UPDATE products SET ..."); err != nil {
+//			return err
+//		}
+//		pg.AfterCommit(ctx, func(ctx context.Context) { cache.Invalidate(product.ID) })
+//		return nil
+//	})
+func RunInTx(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) error) error {
+	return WithinTx(ctx, func(ctx context.Context) error {
+		return fn(ctx, scopeFrom(ctx).tx)
+	})
+}
+
+// AfterCommit registers a callback to run after the transaction of the
+// context (see WithinTx) commits, e.g. invalidating caches or publishing
+// events, so that they are never observed before the changes. The callbacks
+// run in order of registration. If the context carries no transaction, the
+// callback runs immediately.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	scope := scopeFrom(ctx)
+	if scope == nil {
+		fn(ctx)
+		return
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	scope.onCommit = append(scope.onCommit, fn)
+}
+
+// AfterRollback registers a callback to run after the transaction of the
+// context (see WithinTx) is rolled back, including failing to commit. The
+// callbacks run in order of registration. If the context carries no
+// transaction, the callback never runs.
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	scope := scopeFrom(ctx)
+	if scope == nil {
+		return
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	scope.onRollback = append(scope.onRollback, fn)
+}
+
+// OnCommit registers a callback to run after the transaction of the context
+// commits, e.g. pushing the changes to a search engine. The callbacks don't
+// run if the transaction is rolled back, which prevents phantom index
+// entries. It's the same as AfterCommit.
+func OnCommit(ctx context.Context, fn func(ctx context.Context)) {
+	AfterCommit(ctx, fn)
+}
@@ -0,0 +1,68 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// unaccentAvailable tells whether the unaccent extension is installed, see
+// DetectUnaccent. It's assumed until detected.
+var unaccentAvailable = true
+
+// DetectUnaccent detects whether the unaccent extension is installed, at the
+// startup. Without it, WithUnaccentSearch falls back to the search without
+// unaccent() rather than failing the queries. Returns whether it's
+// installed. See EnsureExtensions to install it.
+//
+// Example:
+//
+//	if ok, err := pg.DetectUnaccent(ctx); err == nil && !ok {
+//		log.Print("unaccent is not installed, the searches are accent-sensitive")
+//	}
+func DetectUnaccent(ctx context.Context) (bool, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	var installed bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'unaccent')").Scan(&installed); err != nil {
+		return false, fmt.Errorf("detect unaccent: %w", err)
+	}
+	unaccentAvailable = installed
+	return installed, nil
+}
+
+// WithUnaccentSearch returns a ListOption that filters the rows whose column
+// contains the term regardless of the accents, i.e. unaccent(column) LIKE
+// unaccent($1), so that "jose" matches "José". With ignoreCase, both sides
+// are lowered too. The wildcards in the term are escaped, as WithContains.
+// The unaccent() function isn't immutable, thus the column isn't indexed
+// unless by an index on the same expression through an immutable wrapper.
+//
+// When the given term is empty, the returned ListOption is a no-op.
+//
+// Example:
+//
+//	pagination, err := pg.List(ctx, users, pg.SQL.Select("*").From("users"),
+//		pg.WithUnaccentSearch("name", term, true),
+//	)
+func WithUnaccentSearch(columnName, term string, ignoreCase bool) ListOption {
+	return filterOn(columnName, func(sb sq.SelectBuilder) sq.SelectBuilder {
+		// noop
+		if term == "" {
+			return sb
+		}
+		column, pattern := columnName, "?"
+		if unaccentAvailable {
+			column, pattern = "unaccent("+column+")", "unaccent(?)"
+		}
+		if ignoreCase {
+			column, pattern = "lower("+column+")", "lower("+pattern+")"
+		}
+		return sb.Where(column+" LIKE "+pattern, "%"+escapeLike(term)+"%")
+	})
+}
@@ -0,0 +1,89 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Update updates the rows of the table matching the condition with the
+// changes, and returns the updated rows (RETURNING *). The changes are either
+// a map of the columns to the values, or a struct (or a pointer to it) whose
+// non-zero fields are the changes, mapped to the columns as Insert does.
+//
+// If allowedColumns are given, the changes of the other columns are rejected,
+// e.g. to keep the clients from updating the columns they shouldn't. A nil
+// condition is rejected to prevent accidental mass updates, use sq.Expr("true")
+// to update all the rows on purpose.
+//
+// Example:
+//
+//	users, err := pg.Update[*User](ctx, "users", sq.Eq{"id": id}, map[string]any{
+//		"name": "John",
+//	}, "name", "email")
+func Update[T any](ctx context.Context, table string, where sq.Sqlizer, changes any, allowedColumns ...string) ([]T, error) {
+	if where == nil {
+		return nil, errors.New("update without condition")
+	}
+	setMap, err := changeColumns(changes)
+	if err != nil {
+		return nil, err
+	}
+	if len(setMap) == 0 {
+		return nil, errors.New("no changes")
+	}
+	if len(allowedColumns) > 0 {
+		allowed := make(map[string]bool, len(allowedColumns))
+		for _, col := range allowedColumns {
+			allowed[col] = true
+		}
+		for _, col := range sortedKeys(setMap) {
+			if !allowed[col] {
+				return nil, fmt.Errorf("column %q is not updatable", col)
+			}
+		}
+	}
+
+	query := SQL.Update(QuoteIdent(table)).Where(where).Suffix("RETURNING *")
+	for _, col := range sortedKeys(setMap) {
+		query = query.Set(QuoteIdent(col), setMap[col])
+	}
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble update query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var updated []T
+	if err := scanAPI(&updated).Select(ctx, q, &updated, sqlstr, args...); err != nil {
+		return nil, fmt.Errorf("update %s: %w", table, err)
+	}
+	return updated, nil
+}
+
+// changeColumns returns the changes of the columns, from a map or a struct.
+func changeColumns(changes any) (map[string]any, error) {
+	switch changes := changes.(type) {
+	case map[string]any:
+		return changes, nil
+	case sq.Eq:
+		return changes, nil
+	}
+
+	columns, values, err := structColumns(changes, true)
+	if err != nil {
+		return nil, err
+	}
+	setMap := make(map[string]any, len(columns))
+	for i, col := range columns {
+		setMap[col] = values[i]
+	}
+	return setMap, nil
+}
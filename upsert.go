@@ -0,0 +1,119 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+)
+
+// UpsertOption configures an Upsert.
+type UpsertOption func(*upsertConfig)
+
+type upsertConfig struct {
+	doNothing      bool
+	excluded       map[string]bool
+	indexPredicate string
+}
+
+// UpsertDoNothing makes an Upsert leave the conflicting row as it is (ON
+// CONFLICT DO NOTHING).
+func UpsertDoNothing() UpsertOption {
+	return func(c *upsertConfig) {
+		c.doNothing = true
+	}
+}
+
+// UpsertExcluding excludes the columns from the update set of an Upsert, i.e.
+// they are only written on insertion, e.g. created_at.
+func UpsertExcluding(columns ...string) UpsertOption {
+	return func(c *upsertConfig) {
+		for _, col := range columns {
+			c.excluded[col] = true
+		}
+	}
+}
+
+// UpsertWhere sets the predicate of the partial unique index to conflict on
+// (ON CONFLICT (...) WHERE predicate), e.g. the unique emails of the users
+// not deleted, created by CREATE UNIQUE INDEX ... ON users (email) WHERE
+// deleted_at IS NULL. The predicate must imply the one of the index, thus
+// it's SQL, not bound args.
+//
+// Example:
+//
+//	written, err := pg.Upsert(ctx, "users", []string{"email"}, user, pg.UpsertWhere("deleted_at IS NULL"))
+func UpsertWhere(predicate string) UpsertOption {
+	return func(c *upsertConfig) {
+		c.indexPredicate = predicate
+	}
+}
+
+// Upsert inserts the struct as a row of the table, or updates the existing row
+// conflicting on the columns (INSERT ... ON CONFLICT (...) DO UPDATE SET ...),
+// and scans the written row (RETURNING *) back into it. The columns are mapped
+// from the fields as Insert does. The conflict columns are never updated.
+// Returns false if nothing is written, i.e. a conflict with UpsertDoNothing.
+//
+// Example:
+//
+//	written, err := pg.Upsert(ctx, "users", []string{"email"}, user, pg.UpsertExcluding("created_at"))
+func Upsert[T any](ctx context.Context, table string, conflictColumns []string, v *T, opts ...UpsertOption) (bool, error) {
+	if len(conflictColumns) == 0 {
+		return false, errors.New("no conflict columns")
+	}
+	config := &upsertConfig{excluded: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(config)
+	}
+	for _, col := range conflictColumns {
+		config.excluded[col] = true
+	}
+
+	columns, values, err := structColumns(v, false)
+	if err != nil {
+		return false, err
+	}
+
+	action := "DO NOTHING"
+	if !config.doNothing {
+		var sets []string
+		for _, col := range columns {
+			if !config.excluded[col] {
+				sets = append(sets, fmt.Sprintf("%s = excluded.%s", QuoteIdent(col), QuoteIdent(col)))
+			}
+		}
+		if len(sets) > 0 {
+			action = "DO UPDATE SET " + strings.Join(sets, ", ")
+		}
+	}
+	target := "(" + strings.Join(quoteIdents(conflictColumns), ", ") + ")"
+	if config.indexPredicate != "" {
+		target += " WHERE " + config.indexPredicate
+	}
+	sqlstr, args, err := SQL.Insert(QuoteIdent(table)).
+		Columns(quoteIdents(columns)...).
+		Values(values...).
+		Suffix(fmt.Sprintf("ON CONFLICT %s %s RETURNING *", target, action)).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("assemble upsert query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	err = scanAPI(v).Get(ctx, q, v, sqlstr, args...)
+	if pgxscan.NotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("upsert into %s: %w", table, err)
+	}
+	return true, nil
+}
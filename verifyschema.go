@@ -0,0 +1,166 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TableExpectation declares the columns and the indexes a model expects of its
+// table. See VerifySchema.
+type TableExpectation struct {
+	Table   string
+	Columns map[string]string // the column names to the types, e.g. "bigint", "timestamptz", empty for any type
+	Indexes []string          // the index names
+}
+
+// SchemaDiscrepancy is a difference between a TableExpectation and the
+// schema of the database.
+type SchemaDiscrepancy struct {
+	Table   string
+	Column  string // empty if not about a column
+	Index   string // empty if not about an index
+	Problem string
+}
+
+func (d SchemaDiscrepancy) String() string {
+	switch {
+	case d.Column != "":
+		return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Problem)
+	case d.Index != "":
+		return fmt.Sprintf("%s (index %s): %s", d.Table, d.Index, d.Problem)
+	default:
+		return fmt.Sprintf("%s: %s", d.Table, d.Problem)
+	}
+}
+
+// VerifySchema compares the expectations of the models with the schema of the
+// database, and returns the discrepancies, e.g. missing tables, columns and
+// indexes, or columns of other types, so that the missing migrations are
+// surfaced at the startup before the traffic hits them. The extra columns and
+// indexes are fine.
+//
+// The types are compared after resolving the common aliases, e.g. "int8" is
+// "bigint", "timestamptz" is "timestamp with time zone", and a type without a
+// modifier matches any modifier, e.g. "varchar" matches "varchar(255)".
+//
+// Example:
+//
+//	discrepancies, err := pg.VerifySchema(ctx, pg.TableExpectation{
+//		Table:   "users",
+//		Columns: map[string]string{"id": "bigint", "email": "text", "created_at": "timestamptz"},
+//		Indexes: []string{"users_email_key"},
+//	})
+//	for _, d := range discrepancies {
+//		log.Printf("schema drift: %s", d)
+//	}
+func VerifySchema(ctx context.Context, expectations ...TableExpectation) ([]SchemaDiscrepancy, error) {
+	q, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var discrepancies []SchemaDiscrepancy
+	for _, exp := range expectations {
+		var exists bool
+		if err := q.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", exp.Table).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("check table %s: %w", exp.Table, err)
+		}
+		if !exists {
+			discrepancies = append(discrepancies, SchemaDiscrepancy{Table: exp.Table, Problem: "table does not exist"})
+			continue
+		}
+
+		rows, err := q.Query(ctx, `SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute
+			WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped`, exp.Table)
+		if err != nil {
+			return nil, fmt.Errorf("load columns of %s: %w", exp.Table, err)
+		}
+		columns := make(map[string]string)
+		var name, typ string
+		if _, err := pgx.ForEachRow(rows, []any{&name, &typ}, func() error {
+			columns[name] = typ
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("load columns of %s: %w", exp.Table, err)
+		}
+		expectedColumns := make([]string, 0, len(exp.Columns))
+		for col := range exp.Columns {
+			expectedColumns = append(expectedColumns, col)
+		}
+		sort.Strings(expectedColumns)
+		for _, col := range expectedColumns {
+			expected := exp.Columns[col]
+			actual, ok := columns[col]
+			switch {
+			case !ok:
+				discrepancies = append(discrepancies, SchemaDiscrepancy{Table: exp.Table, Column: col, Problem: "column does not exist"})
+			case expected != "" && !sameType(expected, actual):
+				discrepancies = append(discrepancies, SchemaDiscrepancy{
+					Table: exp.Table, Column: col,
+					Problem: fmt.Sprintf("expect type %s, got %s", expected, actual),
+				})
+			}
+		}
+
+		rows, err = q.Query(ctx, `SELECT c.relname FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE i.indrelid = to_regclass($1)`, exp.Table)
+		if err != nil {
+			return nil, fmt.Errorf("load indexes of %s: %w", exp.Table, err)
+		}
+		indexes := make(map[string]bool)
+		if _, err := pgx.ForEachRow(rows, []any{&name}, func() error {
+			indexes[name] = true
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("load indexes of %s: %w", exp.Table, err)
+		}
+		for _, index := range exp.Indexes {
+			if !indexes[index] {
+				discrepancies = append(discrepancies, SchemaDiscrepancy{Table: exp.Table, Index: index, Problem: "index does not exist"})
+			}
+		}
+	}
+	return discrepancies, nil
+}
+
+// typeAliases maps the aliases of the types to the names format_type returns.
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"int8":        "bigint",
+	"int2":        "smallint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+	"decimal":     "numeric",
+}
+
+func sameType(expected, actual string) bool {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if alias, ok := typeAliases[expected]; ok {
+		expected = alias
+	}
+	if expected == actual {
+		return true
+	}
+	// A type without a modifier matches any modifier. The modifiers of the
+	// time types go in the middle, e.g. "timestamp(3) with time zone".
+	if before, after, ok := strings.Cut(actual, "("); ok {
+		_, after, _ = strings.Cut(after, ")")
+		return expected == before+after
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package pg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// WebhooksSchema is the DDL of the table storing the outbound webhook
+// deliveries. Run it along with the migrations of the application.
+const WebhooksSchema = `
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id               bigserial PRIMARY KEY,
+	url              text NOT NULL,
+	event            text NOT NULL,
+	payload          jsonb NOT NULL,
+	status           text NOT NULL DEFAULT 'pending',
+	attempts         int NOT NULL DEFAULT 0,
+	next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+	locked_until     timestamptz,
+	last_status_code int,
+	last_error       text,
+	created_at       timestamptz NOT NULL DEFAULT now(),
+	delivered_at     timestamptz
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+`
+
+// The statuses of the webhook deliveries.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead" // exhausted the attempts
+)
+
+// WebhookSignatureHeader is the header carrying the hex-encoded HMAC-SHA256
+// signature of the request body, when WebhookQueue.Secret is set.
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookDelivery is an outbound webhook delivery.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	URL            string          `json:"url"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LockedUntil    *time.Time      `json:"locked_until"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// NamingStrategy implements ModelNaming, the columns of the webhook_deliveries
+// table are in snake case regardless of the global naming strategy.
+func (WebhookDelivery) NamingStrategy() NamingStrategy { return SnakeCase }
+
+// Webhooks is the webhook delivery queue backed by the webhook_deliveries
+// table (see WebhooksSchema), with the default settings.
+//
+// Example:
+//
+//	go pg.Webhooks.Work(ctx, 4)
+//	id, err := pg.Webhooks.Enqueue(ctx, endpoint, "order.paid", order)
+var Webhooks = new(WebhookQueue).withDefaults()
+
+// WebhookQueue stores the outbound webhook deliveries and delivers them with
+// retries. A failed attempt is retried after an exponential backoff with
+// jitter, the deliveries exhausted the attempts are dead-lettered. The zero
+// settings mean the defaults: 10 attempts, backing off from 10 seconds up to
+// 6 hours, and a timeout of 30 seconds.
+type WebhookQueue struct {
+	MaxAttempts int
+	BaseBackoff time.Duration // backoff after the first failed attempt
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // timeout of an attempt, also the lease of a claimed delivery
+	Secret      string        // signs the requests if set, see WebhookSignatureHeader
+	Client      *http.Client  // http.DefaultClient if nil
+}
+
+// Enqueue stores a delivery of the event to the URL. The payload is sent as
+// the JSON body of a POST request.
+func (q *WebhookQueue) Enqueue(ctx context.Context, url, event string, payload any) (int64, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("marshal webhook payload: %w", err)
+	}
+	sqlstr, args, err := SQL.Insert("webhook_deliveries").
+		Columns("url", "event", "payload").
+		Values(url, event, raw).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble insert query: %w", err)
+	}
+
+	db, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var id int64
+	if err := db.QueryRow(ctx, sqlstr, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("enqueue webhook: %w", err)
+	}
+	return id, nil
+}
+
+// Get returns a delivery by id. Returns nil if not found.
+func (q *WebhookQueue) Get(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	query := SQL.Select("*").From("webhook_deliveries").Where(sq.Eq{"id": id})
+	return Get(ctx, new(WebhookDelivery), query)
+}
+
+// ListByStatus returns up to limit deliveries of the status, the latest first.
+func (q *WebhookQueue) ListByStatus(ctx context.Context, status string, limit uint64) ([]*WebhookDelivery, error) {
+	sqlstr, args, err := SQL.Select("*").From("webhook_deliveries").
+		Where(sq.Eq{"status": status}).
+		OrderBy("id DESC").
+		Limit(limit).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("assemble query: %w", err)
+	}
+
+	db, release, err := querier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var deliveries []*WebhookDelivery
+	if err := scanAPI(&deliveries).Select(ctx, db, &deliveries, sqlstr, args...); err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver puts a dead (or delivered) delivery back to the queue, with the
+// attempts reset. Returns false if the delivery is not found or pending.
+func (q *WebhookQueue) Redeliver(ctx context.Context, id int64) (bool, error) {
+	n, err := Exec(ctx, SQL.Update("webhook_deliveries").
+		Set("status", WebhookPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"status": WebhookPending}))
+	if err != nil {
+		return false, fmt.Errorf("redeliver webhook: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Work delivers the due deliveries with the given concurrency, until the
+// context is done.
+func (q *WebhookQueue) Work(ctx context.Context, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	q = q.withDefaults()
+	for {
+		deliveries, err := q.claim(ctx, uint64(concurrency))
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func(d *WebhookDelivery) {
+				defer wg.Done()
+				q.deliver(ctx, d) // retried after the lease on failure
+			}(d)
+		}
+		wg.Wait()
+
+		if len(deliveries) == 0 || err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+// withDefaults returns a copy of the queue whose zero settings are set to the
+// defaults.
+func (q *WebhookQueue) withDefaults() *WebhookQueue {
+	c := *q
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 10
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = 10 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 6 * time.Hour
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	return &c
+}
+
+func (q *WebhookQueue) claim(ctx context.Context, n uint64) ([]*WebhookDelivery, error) {
+	query := SQL.Select("*").From("webhook_deliveries").
+		Where(sq.Eq{"status": WebhookPending}).
+		Where("next_attempt_at <= now()").
+		Where("(locked_until IS NULL OR locked_until < now())").
+		OrderBy("next_attempt_at")
+	return ClaimRows[*WebhookDelivery](ctx, query, n, func(ub sq.UpdateBuilder) sq.UpdateBuilder {
+		return ub.Set("locked_until", sq.Expr("now() + ? * interval '1 second'", 2*q.Timeout.Seconds()))
+	})
+}
+
+// deliver makes an attempt of the delivery and records the result.
+func (q *WebhookQueue) deliver(ctx context.Context, d *WebhookDelivery) error {
+	statusCode, sendErr := q.send(ctx, d)
+	attempts := d.Attempts + 1
+
+	update := SQL.Update("webhook_deliveries").
+		Set("attempts", attempts).
+		Set("locked_until", nil).
+		Where(sq.Eq{"id": d.ID})
+	if statusCode > 0 {
+		update = update.Set("last_status_code", statusCode)
+	}
+
+	switch {
+	case sendErr == nil:
+		update = update.
+			Set("status", WebhookDelivered).
+			Set("delivered_at", sq.Expr("now()")).
+			Set("last_error", nil)
+	case attempts >= q.MaxAttempts:
+		update = update.
+			Set("status", WebhookDead).
+			Set("last_error", sendErr.Error())
+	default:
+		update = update.
+			Set("next_attempt_at", time.Now().Add(q.backoff(attempts))).
+			Set("last_error", sendErr.Error())
+	}
+	_, err := Exec(ctx, update)
+	return err
+}
+
+func (q *WebhookQueue) send(ctx context.Context, d *WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", fmt.Sprint(d.ID))
+	if q.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(q.Secret))
+		mac.Write(d.Payload)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := q.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (q *WebhookQueue) backoff(attempts int) time.Duration {
+	return backoffDelay(q.BaseBackoff, q.MaxBackoff, attempts)
+}
+
+// backoffDelay returns the delay before the next attempt: exponential, capped
+// by max, and jittered between the half and the full of it.
+func backoffDelay(base, max time.Duration, attempts int) time.Duration {
+	backoff := float64(base) * math.Pow(2, float64(attempts-1))
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+	return time.Duration(backoff/2 + rand.Float64()*backoff/2)
+}
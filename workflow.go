@@ -0,0 +1,260 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+)
+
+// WorkflowsSchema is the DDL of the tables storing the workflow instances and
+// their state transitions. Run it along with the migrations of the application.
+const WorkflowsSchema = `
+CREATE TABLE IF NOT EXISTS workflows (
+	id           bigserial PRIMARY KEY,
+	type         text NOT NULL,
+	state        text NOT NULL,
+	data         jsonb NOT NULL DEFAULT '{}',
+	run_at       timestamptz NOT NULL DEFAULT now(),
+	deadline_at  timestamptz,
+	locked_until timestamptz,
+	created_at   timestamptz NOT NULL DEFAULT now(),
+	updated_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS workflows_run_at_idx ON workflows (type, run_at);
+CREATE TABLE IF NOT EXISTS workflow_transitions (
+	id          bigserial PRIMARY KEY,
+	workflow_id bigint NOT NULL REFERENCES workflows (id) ON DELETE CASCADE,
+	from_state  text NOT NULL,
+	to_state    text NOT NULL,
+	created_at  timestamptz NOT NULL DEFAULT now()
+);
+`
+
+var (
+	// ErrIllegalTransition is returned when a transition is not allowed by
+	// the workflow definition.
+	ErrIllegalTransition = errors.New("pg: illegal workflow transition")
+
+	// ErrStateChanged is returned when the workflow instance is not in the
+	// expected state anymore, i.e. it has been transitioned concurrently.
+	ErrStateChanged = errors.New("pg: workflow state changed")
+)
+
+// WorkflowInstance is a persisted instance of a workflow.
+type WorkflowInstance struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	State       string          `json:"state"`
+	Data        json.RawMessage `json:"data"`
+	RunAt       time.Time       `json:"run_at"`
+	DeadlineAt  *time.Time      `json:"deadline_at"`
+	LockedUntil *time.Time      `json:"locked_until"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// NamingStrategy implements ModelNaming, the columns of the workflows table are
+// in snake case regardless of the global naming strategy.
+func (WorkflowInstance) NamingStrategy() NamingStrategy { return SnakeCase }
+
+// Workflow defines a state machine, whose instances are persisted in the
+// workflows table (see WorkflowsSchema).
+//
+// Example:
+//
+//	var checkout = &pg.Workflow{
+//		Type:    "checkout",
+//		Initial: "reserving",
+//		Transitions: map[string][]string{
+//			"reserving": {"charging", "cancelled"},
+//			"charging":  {"completed", "releasing"},
+//			"releasing": {"cancelled"},
+//		},
+//		Timeouts: map[string]time.Duration{"charging": 10 * time.Minute},
+//		OnTimeout: func(ctx context.Context, w *pg.WorkflowInstance) error {
+//			return checkout.Transition(ctx, w.ID, w.State, "releasing", nil)
+//		},
+//	}
+type Workflow struct {
+	Type    string
+	Initial string
+
+	// Transitions are the allowed transitions, from a state to the states.
+	// The states without outgoing transitions are terminal.
+	Transitions map[string][]string
+
+	// Timeouts are the max durations the instances can stay in the states.
+	Timeouts map[string]time.Duration
+
+	// OnTimeout is the compensation hook called with the instances that
+	// stayed in a state longer than its timeout, see HandleTimeouts.
+	OnTimeout func(ctx context.Context, w *WorkflowInstance) error
+}
+
+// Start creates an instance in the initial state. The data is stored as JSON.
+func (wf *Workflow) Start(ctx context.Context, data any) (int64, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return 0, fmt.Errorf("marshal workflow data: %w", err)
+	}
+	sqlstr, args, err := SQL.Insert("workflows").
+		Columns("type", "state", "data", "deadline_at").
+		Values(wf.Type, wf.Initial, raw, wf.deadline(wf.Initial)).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("assemble insert query: %w", err)
+	}
+
+	q, release, err := querier(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	var id int64
+	if err := q.QueryRow(ctx, sqlstr, args...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("start workflow: %w", err)
+	}
+	return id, nil
+}
+
+// Transition moves the instance from a state to another. Returns
+// ErrIllegalTransition if it's not allowed, ErrStateChanged if the instance is
+// not in the from state. A non-nil data replaces the data of the instance.
+// The lock of the instance (see ClaimNext) is released.
+func (wf *Workflow) Transition(ctx context.Context, id int64, from, to string, data any) error {
+	if !wf.allowed(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, from, to)
+	}
+
+	query := SQL.Update("workflows").
+		Set("state", to).
+		Set("deadline_at", wf.deadline(to)).
+		Set("run_at", sq.Expr("now()")).
+		Set("locked_until", nil).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id, "type": wf.Type, "state": from})
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("marshal workflow data: %w", err)
+		}
+		query = query.Set("data", raw)
+	}
+	history := SQL.Insert("workflow_transitions").
+		Columns("workflow_id", "from_state", "to_state").
+		Values(id, from, to)
+
+	return wf.inTx(ctx, func(q Querier) error {
+		if n, err := execQuery(ctx, q, query); err != nil {
+			return fmt.Errorf("transition workflow: %w", err)
+		} else if n == 0 {
+			return ErrStateChanged
+		}
+		if _, err := execQuery(ctx, q, history); err != nil {
+			return fmt.Errorf("record workflow transition: %w", err)
+		}
+		return nil
+	})
+}
+
+// ClaimNext claims up to n due instances in non-terminal states and locks
+// them for the lease duration (SKIP LOCKED). A worker should run the step of
+// each claimed instance and call Transition, the instances not transitioned
+// within the lease become claimable again.
+func (wf *Workflow) ClaimNext(ctx context.Context, n uint64, lease time.Duration) ([]*WorkflowInstance, error) {
+	query := SQL.Select("*").From("workflows").
+		Where(sq.Eq{"type": wf.Type, "state": wf.activeStates()}).
+		Where("run_at <= now()").
+		Where("(locked_until IS NULL OR locked_until < now())").
+		OrderBy("run_at")
+	return wf.claim(ctx, query, n, lease)
+}
+
+// HandleTimeouts claims up to n instances that stayed in a state longer than
+// its timeout, and calls OnTimeout with each of them. Returns the number of
+// the handled instances. The instances failed to be compensated are retried
+// after the lease.
+func (wf *Workflow) HandleTimeouts(ctx context.Context, n uint64, lease time.Duration) (int, error) {
+	if wf.OnTimeout == nil {
+		return 0, errors.New("workflow has no OnTimeout hook")
+	}
+	query := SQL.Select("*").From("workflows").
+		Where(sq.Eq{"type": wf.Type, "state": wf.activeStates()}).
+		Where("deadline_at <= now()").
+		Where("(locked_until IS NULL OR locked_until < now())").
+		OrderBy("deadline_at")
+	instances, err := wf.claim(ctx, query, n, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, instance := range instances {
+		if err := wf.OnTimeout(ctx, instance); err != nil {
+			return i, fmt.Errorf("compensate workflow %d: %w", instance.ID, err)
+		}
+	}
+	return len(instances), nil
+}
+
+func (wf *Workflow) claim(ctx context.Context, query sq.SelectBuilder, n uint64, lease time.Duration) ([]*WorkflowInstance, error) {
+	return ClaimRows[*WorkflowInstance](ctx, query, n, func(ub sq.UpdateBuilder) sq.UpdateBuilder {
+		return ub.Set("locked_until", sq.Expr("now() + ? * interval '1 second'", lease.Seconds()))
+	})
+}
+
+func (wf *Workflow) inTx(ctx context.Context, fn func(Querier) error) error {
+	return RunInTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(withMiddlewares(tx))
+	})
+}
+
+func (wf *Workflow) allowed(from, to string) bool {
+	for _, state := range wf.Transitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// activeStates returns the non-terminal states.
+func (wf *Workflow) activeStates() []string {
+	states := make([]string, 0, len(wf.Transitions))
+	for state, next := range wf.Transitions {
+		if len(next) > 0 {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+// deadline returns the deadline of entering the state now, nil if the state
+// has no timeout.
+func (wf *Workflow) deadline(state string) *time.Time {
+	timeout, ok := wf.Timeouts[state]
+	if !ok {
+		return nil
+	}
+	deadline := time.Now().Add(timeout)
+	return &deadline
+}
+
+// execQuery runs an INSERT/UPDATE/DELETE query with the querier.
+func execQuery(ctx context.Context, q Querier, query sq.Sqlizer) (int64, error) {
+	sqlstr, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.Exec(ctx, sqlstr, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}